@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+	"time"
+)
+
+// Cgroup configures the cgroup v2 in which a command is executed, see WithCgroup
+type Cgroup struct {
+	// Parent is the path of the cgroup in which a new cgroup is created
+	// for every execution, e.g. /sys/fs/cgroup/jobs
+	Parent string
+	// MemoryMax is the memory limit of all processes in bytes, processes are
+	// killed by the out of memory killer if it is exceeded
+	MemoryMax int64
+	// CPUMax is the cpu time all processes can use per CPUPeriod
+	CPUMax time.Duration
+	// CPUPeriod defaults to 100ms
+	CPUPeriod time.Duration
+	// PidsMax is the maximum number of processes
+	PidsMax int64
+	path    string
+	dir     *os.File
+	stats   *CgroupStats
+}
+
+// CgroupStats are read from the cgroup of a command after it exited
+type CgroupStats struct {
+	// MemoryPeak is the maximum memory usage in bytes, it is read from memory.peak
+	// which requires linux 5.19 and the memory controller
+	MemoryPeak int64 `json:"memory_peak"`
+	// OOMKills is the number of processes killed by the out of memory killer
+	OOMKills int64 `json:"oom_kills"`
+}
+
+// CgroupStats returns the stats of the cgroup of the last execution,
+// or nil if the command was not executed in a cgroup, see WithCgroup
+func (c *Command) CgroupStats() *CgroupStats {
+	if c.cgroup == nil || c.cgroup.stats == nil {
+		return nil
+	}
+	stats := *c.cgroup.stats
+	return &stats
+}
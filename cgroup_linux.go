@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgroupRemoveTimeout is the time to wait for the processes of a cgroup
+// to exit after they were killed
+const cgroupRemoveTimeout = 5 * time.Second
+
+// WithCgroup executes the command in a new cgroup v2 which is created in the parent cgroup
+// The process is started in the cgroup, processes started by the command are in the
+// cgroup too. After the command exited its stats are read, see CgroupStats, remaining
+// processes are killed and the cgroup is removed.
+//
+// The parent must be writable and must not contain processes if limits are set, because
+// the controllers of the limits are enabled in its cgroup.subtree_control.
+//
+// Example:
+//
+//	c := cmd.NewCommand("make -j8", cmd.WithCgroup("/sys/fs/cgroup/jobs",
+//	    cmd.WithMemoryMax(2<<30),
+//	    cmd.WithCPUMax(200*time.Millisecond, 100*time.Millisecond),
+//	    cmd.WithPidsMax(256),
+//	))
+//	c.Execute()
+//	if c.CgroupStats().OOMKills > 0 {
+//	    fmt.Println("make ran out of memory")
+//	}
+func WithCgroup(parent string, options ...func(*Cgroup)) func(c *Command) {
+	return func(c *Command) {
+		cg := &Cgroup{Parent: parent}
+		for _, o := range options {
+			o(cg)
+		}
+		if cg.CPUMax > 0 && cg.CPUPeriod == 0 {
+			cg.CPUPeriod = 100 * time.Millisecond
+		}
+
+		switch {
+		case cg.MemoryMax < 0:
+			c.addOptionError(fmt.Errorf("invalid cgroup memory limit %d", cg.MemoryMax))
+		case cg.PidsMax < 0:
+			c.addOptionError(fmt.Errorf("invalid cgroup pids limit %d", cg.PidsMax))
+		case cg.CPUMax < 0 || cg.CPUMax > 0 && cg.CPUMax < time.Millisecond:
+			c.addOptionError(fmt.Errorf("invalid cgroup cpu limit %v, must be at least 1ms", cg.CPUMax))
+		case cg.CPUMax > 0 && (cg.CPUPeriod < time.Millisecond || cg.CPUPeriod > time.Second):
+			c.addOptionError(fmt.Errorf("invalid cgroup cpu period %v, must be between 1ms and 1s", cg.CPUPeriod))
+		}
+		c.cgroup = cg
+	}
+}
+
+// WithMemoryMax limits the memory of the processes in the cgroup in bytes, see WithCgroup
+func WithMemoryMax(bytes int64) func(cg *Cgroup) {
+	return func(cg *Cgroup) {
+		cg.MemoryMax = bytes
+	}
+}
+
+// WithCPUMax limits the processes in the cgroup to max cpu time per period, see WithCgroup
+// A max greater than the period allows to use multiple cpus.
+func WithCPUMax(max, period time.Duration) func(cg *Cgroup) {
+	return func(cg *Cgroup) {
+		cg.CPUMax = max
+		cg.CPUPeriod = period
+	}
+}
+
+// WithPidsMax limits the number of processes in the cgroup, see WithCgroup
+func WithPidsMax(n int64) func(cg *Cgroup) {
+	return func(cg *Cgroup) {
+		cg.PidsMax = n
+	}
+}
+
+// create creates the cgroup, applies the limits and starts cmd in it
+func (cg *Cgroup) create(cmd *exec.Cmd) error {
+	limits := map[string]string{}
+	if cg.MemoryMax > 0 {
+		limits["memory.max"] = strconv.FormatInt(cg.MemoryMax, 10)
+	}
+	if cg.CPUMax > 0 {
+		limits["cpu.max"] = fmt.Sprintf("%d %d", cg.CPUMax.Microseconds(), cg.CPUPeriod.Microseconds())
+	}
+	if cg.PidsMax > 0 {
+		limits["pids.max"] = strconv.FormatInt(cg.PidsMax, 10)
+	}
+
+	for file := range limits {
+		controller, _, _ := strings.Cut(file, ".")
+		if err := enableController(cg.Parent, controller); err != nil {
+			return err
+		}
+	}
+
+	path, err := os.MkdirTemp(cg.Parent, "cmd-")
+	if err != nil {
+		return fmt.Errorf("can not create cgroup: %w", err)
+	}
+	cg.path = path
+	cg.stats = nil
+
+	for file, value := range limits {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(value), 0); err != nil {
+			os.Remove(path)
+			return fmt.Errorf("can not set %s of cgroup %s: %w", file, path, err)
+		}
+	}
+
+	cg.dir, err = os.Open(path)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("can not open cgroup: %w", err)
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.dir.Fd())
+	return nil
+}
+
+// remove reads the stats, kills the remaining processes and removes the cgroup
+func (cg *Cgroup) remove() error {
+	cg.dir.Close()
+	cg.stats = &CgroupStats{
+		MemoryPeak: readCgroupInt(cg.path, "memory.peak", ""),
+		OOMKills:   readCgroupInt(cg.path, "memory.events", "oom_kill"),
+	}
+
+	if err := killCgroup(cg.path); err != nil {
+		return err
+	}
+	if err := os.Remove(cg.path); err != nil {
+		return fmt.Errorf("can not remove cgroup: %w", err)
+	}
+	return nil
+}
+
+// enableController enables a controller for the children of the parent cgroup
+func enableController(parent, controller string) error {
+	enabled, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return fmt.Errorf("can not read controllers of cgroup %s: %w", parent, err)
+	}
+	if containsWord(enabled, controller) {
+		return nil
+	}
+
+	available, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("can not read controllers of cgroup %s: %w", parent, err)
+	}
+	if !containsWord(available, controller) {
+		return fmt.Errorf("cgroup controller %s is not available in %s", controller, parent)
+	}
+
+	err = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+"+controller), 0)
+	if err != nil {
+		return fmt.Errorf("can not enable cgroup controller %s in %s: %w", controller, parent, err)
+	}
+	return nil
+}
+
+// killCgroup kills all processes in the cgroup and waits until they exited
+func killCgroup(path string) error {
+	// cgroup.kill requires linux 5.14
+	err := os.WriteFile(filepath.Join(path, "cgroup.kill"), []byte("1"), 0)
+	if errors.Is(err, os.ErrNotExist) {
+		procs, _ := os.ReadFile(filepath.Join(path, "cgroup.procs"))
+		for _, pid := range strings.Fields(string(procs)) {
+			if pid, err := strconv.Atoi(pid); err == nil {
+				syscall.Kill(pid, syscall.SIGKILL)
+			}
+		}
+	} else if err != nil {
+		return fmt.Errorf("can not kill processes of cgroup %s: %w", path, err)
+	}
+
+	deadline := time.Now().Add(cgroupRemoveTimeout)
+	for readCgroupInt(path, "cgroup.events", "populated") != 0 {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("processes of cgroup %s did not exit after %v", path, cgroupRemoveTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// readCgroupInt reads a number from a cgroup file, if key is set the file is read
+// as "key value" lines. It returns 0 if the file or key does not exist.
+func readCgroupInt(path, file, key string) int64 {
+	data, err := os.ReadFile(filepath.Join(path, file))
+	if err != nil {
+		return 0
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if key != "" {
+			if len(fields) != 2 || fields[0] != key {
+				continue
+			}
+			fields = fields[1:]
+		}
+		if len(fields) == 1 {
+			n, _ := strconv.ParseInt(fields[0], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+func containsWord(data []byte, word string) bool {
+	for _, w := range strings.Fields(string(data)) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cgroupParent creates a parent cgroup for a test in the cgroup of the test process
+func cgroupParent(t *testing.T, controllers ...string) string {
+	mount := cgroup2Mount()
+	if mount == "" {
+		t.Skip("cgroup2 is not mounted")
+	}
+
+	self, err := os.ReadFile("/proc/self/cgroup")
+	require.NoError(t, err)
+	var current string
+	for _, line := range strings.Split(string(self), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			current = path
+		}
+	}
+
+	parent, err := os.MkdirTemp(filepath.Join(mount, current), "cmd-test-")
+	if err != nil {
+		t.Skipf("cgroupfs is not writable: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(parent) })
+
+	available, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	require.NoError(t, err)
+	for _, controller := range controllers {
+		if !containsWord(available, controller) {
+			t.Skipf("cgroup controller %s is not available", controller)
+		}
+	}
+	return parent
+}
+
+func cgroup2Mount() string {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The mount point is the fifth field, the filesystem type follows the separator
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" {
+				return fields[4]
+			}
+		}
+	}
+	return ""
+}
+
+func childCgroups(t *testing.T, parent string) []string {
+	entries, err := os.ReadDir(parent)
+	require.NoError(t, err)
+
+	var children []string
+	for _, e := range entries {
+		if e.IsDir() {
+			children = append(children, e.Name())
+		}
+	}
+	return children
+}
+
+func TestCommand_WithCgroup(t *testing.T) {
+	parent := cgroupParent(t)
+
+	c := NewCommand("grep ^0:: /proc/self/cgroup", WithCgroup(parent))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.ExitCode(), c.Stderr())
+	assert.Contains(t, c.Stdout(), "/"+filepath.Base(parent)+"/cmd-")
+	assert.Empty(t, childCgroups(t, parent))
+	assert.NotNil(t, c.CgroupStats())
+	assert.NotNil(t, c.Result().Cgroup)
+}
+
+func TestCommand_WithCgroupKillsRemainingProcesses(t *testing.T) {
+	parent := cgroupParent(t)
+
+	c := NewCommand("sleep 60 >/dev/null 2>&1 & echo $!", WithCgroup(parent))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(c.Stdout()))
+	require.NoError(t, err)
+
+	// The process was killed, it may still wait to be reaped by init
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err == nil {
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		assert.Equal(t, "Z", fields[0])
+	}
+	assert.Empty(t, childCgroups(t, parent))
+}
+
+func TestCommand_WithCgroupTimeout(t *testing.T) {
+	parent := cgroupParent(t)
+
+	c := NewCommand("sleep 60 >/dev/null 2>&1 & sleep 60", WithCgroup(parent), WithTimeout(100*time.Millisecond))
+	err := c.Execute()
+
+	assert.EqualError(t, err, "command timed out after 100ms")
+	assert.Empty(t, childCgroups(t, parent))
+}
+
+func TestCommand_WithCgroupLimits(t *testing.T) {
+	parent := cgroupParent(t, "memory", "cpu", "pids")
+
+	script := `dir=%s$(grep ^0:: /proc/self/cgroup | cut -d: -f3); cat $dir/memory.max $dir/cpu.max $dir/pids.max`
+
+	c := NewCommand(fmt.Sprintf(script, cgroup2Mount()),
+		WithCgroup(parent,
+			WithMemoryMax(64<<20),
+			WithCPUMax(50*time.Millisecond, 100*time.Millisecond),
+			WithPidsMax(32),
+		),
+	)
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "67108864\n50000 100000\n32\n", c.Stdout(), c.Stderr())
+}
+
+func TestCommand_WithCgroupMemoryMax(t *testing.T) {
+	parent := cgroupParent(t, "memory")
+
+	c := NewCommand(`x=$(head -c 134217728 /dev/zero | tr '\0' a)`, WithCgroup(parent, WithMemoryMax(32<<20)))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, c.ExitCode())
+	assert.GreaterOrEqual(t, c.CgroupStats().OOMKills, int64(1))
+	assert.Greater(t, c.CgroupStats().MemoryPeak, int64(0))
+}
+
+func TestCommand_WithCgroupPidsMax(t *testing.T) {
+	parent := cgroupParent(t, "pids")
+
+	c := NewCommand("sleep 1 & sleep 1 & sleep 1 & wait", WithCgroup(parent, WithPidsMax(2)))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, c.ExitCode())
+}
+
+func TestCommand_WithCgroupControllerNotAvailable(t *testing.T) {
+	parent := cgroupParent(t)
+	available, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	require.NoError(t, err)
+	if containsWord(available, "pids") {
+		t.Skip("pids controller is available")
+	}
+
+	c := NewCommand("echo hello", WithCgroup(parent, WithPidsMax(10)))
+	err = c.Execute()
+
+	assert.EqualError(t, err, "cgroup controller pids is not available in "+parent)
+	assert.False(t, c.Executed())
+	assert.Empty(t, childCgroups(t, parent))
+}
+
+func TestCommand_WithCgroupInvalidLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(*Cgroup)
+		err    string
+	}{
+		{"memory", WithMemoryMax(-1), "invalid cgroup memory limit -1"},
+		{"pids", WithPidsMax(-1), "invalid cgroup pids limit -1"},
+		{"cpu", WithCPUMax(time.Microsecond, 0), "invalid cgroup cpu limit 1µs, must be at least 1ms"},
+		{"cpu period", WithCPUMax(time.Second, 2*time.Second), "invalid cgroup cpu period 2s, must be between 1ms and 1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCommand("echo hello", WithCgroup("/sys/fs/cgroup", tt.option))
+			err := c.Execute()
+
+			assert.EqualError(t, err, tt.err)
+			assert.False(t, c.Executed())
+		})
+	}
+}
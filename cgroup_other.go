@@ -0,0 +1,16 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func (cg *Cgroup) create(cmd *exec.Cmd) error {
+	return errors.New("cgroups are only supported on linux")
+}
+
+func (cg *Cgroup) remove() error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"syscall"
+)
+
+// WithChroot executes the command with dir as its root directory
+// The working dir is relative to the new root and defaults to /. Before the
+// command is started it is checked that the shell, or the program of commands
+// created with NewCommandArgs, exists in the root, see Validate.
+// The program of commands created with NewCommandArgs is looked up in the PATH
+// of the current process, use an absolute path inside of the root instead.
+// Symbolic links are resolved inside of the root.
+//
+// The root is not made read-only, the command can write to it unless dir
+// is mounted read-only, e.g. with a read-only bind mount.
+//
+// Changing the root requires CAP_SYS_CHROOT, or a user namespace, see WithNamespaces.
+//
+// Example:
+//
+//	c := cmd.NewCommand("make", cmd.WithChroot("/srv/buildroot"), cmd.WithWorkingDir("/src"))
+//	c.Execute()
+func WithChroot(dir string) func(c *Command) {
+	return func(c *Command) {
+		c.chroot = dir
+		if c.tempDir != nil {
+			c.addOptionError(errTempDirWithChroot)
+		}
+		WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+			attr.Chroot = dir
+		})(c)
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createRoot creates a root dir which contains /bin/sh and the libraries it is linked with
+func createRoot(t *testing.T) string {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the root requires root")
+	}
+	if _, err := exec.LookPath("ldd"); err != nil {
+		t.Skip("ldd is not installed")
+	}
+
+	root := t.TempDir()
+	files := []string{"/bin/sh"}
+
+	c := NewCommand("ldd /bin/sh")
+	require.NoError(t, c.Execute())
+	for _, field := range strings.Fields(c.Stdout()) {
+		if filepath.IsAbs(field) {
+			files = append(files, field)
+		}
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(file)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, file), data, 0755))
+	}
+	return root
+}
+
+func TestCommand_WithChroot(t *testing.T) {
+	root := createRoot(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "marker"), []byte("inside"), 0644))
+
+	c := NewCommand("read line < /marker; echo $line; pwd", WithChroot(root))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "inside\n/\n", c.Stdout(), c.Stderr())
+}
+
+func TestCommand_WithChrootWorkingDir(t *testing.T) {
+	root := createRoot(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src", "app"), 0755))
+
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{"", "/"},
+		{"/src", "/src"},
+		{"src/app", "/src/app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			c := NewCommand("pwd", WithChroot(root), WithWorkingDir(tt.dir), WithPreflight)
+			err := c.Execute()
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected+"\n", c.Stdout(), c.Stderr())
+		})
+	}
+}
+
+func TestCommand_WithChrootWithoutShell(t *testing.T) {
+	root := t.TempDir()
+
+	c := NewCommand("echo hello", WithChroot(root))
+	err := c.Execute()
+
+	var execErr *ExecutableError
+	require.True(t, errors.As(err, &execErr))
+	assert.Equal(t, "/bin/sh", execErr.Name)
+	assert.Equal(t, root, execErr.Root)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.EqualError(t, err, `executable "/bin/sh" in chroot "`+root+`": file does not exist`)
+	assert.False(t, c.Executed())
+}
+
+func TestCommand_ValidateWithChroot(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "bin"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "bin", "sh"), nil, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "bin", "tool"), nil, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "build.sh"), nil, 0755))
+
+	tests := []struct {
+		name    string
+		command string
+		dir     string
+		err     string
+	}{
+		{"builtin", "echo hello", "", ""},
+		{"in PATH of the root", "tool --help", "", ""},
+		{"absolute path", "/bin/tool", "", ""},
+		{"relative to working dir", "./build.sh", "/src", ""},
+		{"not in root", "ls", "", `executable "ls" in chroot "` + root + `": executable file not found in $PATH`},
+		{"working dir not in root", "echo hello", "/missing", `working dir "/missing": no such file or directory`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCommand(tt.command, WithChroot(root), WithWorkingDir(tt.dir), WithEnvironmentVariables(EnvVars{"PATH": "/usr/bin:/bin"}))
+			err := c.Validate()
+
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCommand_WithChrootAndTempWorkingDir(t *testing.T) {
+	c := NewCommand("pwd", WithChroot(t.TempDir()), WithTempWorkingDir(""))
+	assert.ErrorIs(t, c.Execute(), errTempDirWithChroot)
+
+	c = NewCommand("pwd", WithTempWorkingDir(""), WithChroot(t.TempDir()))
+	assert.ErrorIs(t, c.Execute(), errTempDirWithChroot)
+	assert.False(t, c.Executed())
+}
+
+func TestCommand_ValidateWithChrootResolvesLinksInRoot(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "bin"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "opt", "shell"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "opt", "shell", "sh"), nil, 0755))
+	require.NoError(t, os.Symlink("/opt/shell", filepath.Join(root, "usr")))
+	require.NoError(t, os.Symlink("../../bin/tool", filepath.Join(root, "opt", "shell", "tool")))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "bin", "tool"), nil, 0755))
+	require.NoError(t, os.Symlink("loop", filepath.Join(root, "bin", "loop")))
+
+	tests := []struct {
+		name string
+		link string
+		err  string
+	}{
+		// The targets do not exist on the host
+		{"absolute link", "/opt/shell/sh", ""},
+		{"link in path", "/usr/sh", ""},
+		{"relative link", "../opt/shell/sh", ""},
+		{"relative link chain", "/usr/tool", ""},
+		{"dot dot above root", "../../../../opt/shell/sh", ""},
+		// The target exists on the host, but not in the root
+		{"host file", "/bin/dash", "file does not exist"},
+		{"link loop", "/bin/loop", "file does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh := filepath.Join(root, "bin", "sh")
+			os.Remove(sh)
+			require.NoError(t, os.Symlink(tt.link, sh))
+
+			err := NewCommand("echo hello", WithChroot(root)).Validate()
+
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, `executable "/bin/sh" in chroot "`+root+`": `+tt.err)
+			}
+		})
+	}
+}
+
+func TestCommand_WithChrootLinkedShell(t *testing.T) {
+	root := createRoot(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "opt"), 0755))
+	require.NoError(t, os.Rename(filepath.Join(root, "bin", "sh"), filepath.Join(root, "opt", "shell")))
+	require.NoError(t, os.Symlink("/opt/shell", filepath.Join(root, "bin", "sh")))
+
+	c := NewCommand("echo hello", WithChroot(root))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", c.Stdout())
+}
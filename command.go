@@ -6,10 +6,15 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -29,16 +34,52 @@ var _ CommandInterface = (*Command)(nil)
 
 // Command represents a single command which can be executed
 type Command struct {
-	Command      string
+	Command string
+	// Args is set if the command is executed without a shell,
+	// see NewCommandArgs
+	Args         []string
 	Env          []string
 	Dir          string
 	Timeout      time.Duration
 	StderrWriter io.Writer
 	StdoutWriter io.Writer
+	StdinReader  io.Reader
 	WorkingDir   string
 	baseCommand  *exec.Cmd
 	executed     bool
 	exitCode     int
+	err          error
+	startedAt    time.Time
+	finishedAt   time.Time
+	dryRun       *DryRun
+	envExpansion EnvExpansion
+	strictEnv    bool
+	preflight    bool
+	tempDir      *TempDir
+	cgroup       *Cgroup
+	chroot       string
+	// sysProcAttrs are applied to the SysProcAttr of the base command on execution
+	sysProcAttrs []func(*syscall.SysProcAttr)
+	// optionErr holds errors of options which are returned on execution
+	optionErr error
+	// expectedExitCodes are the exit codes which are treated as success
+	expectedExitCodes []int
+	// beforeRun are applied to the process after exec before it runs, see startProcess
+	beforeRun []func(pid int) error
+	// signal is the signal which terminated the process
+	signal syscall.Signal
+	// restrictCaps and noNewPrivs restrict the privileges of the process
+	restrictCaps bool
+	noNewPrivs   bool
+	hooks        []Hooks
+	middlewares  []Middleware
+	logger       *slog.Logger
+	logConfig    *LogConfig
+	// nice and ioPriority are inherited from the thread which starts the process
+	nice       *int
+	ioPriority int
+	// traceParent is added to the env of the process of the current execution, see WithTracer
+	traceParent string
 	// stderr and stdout retrieve the output after the command was executed
 	stderr   bytes.Buffer
 	stdout   bytes.Buffer
@@ -69,16 +110,24 @@ type EnvVars map[string]string
 //	c := cmd.NewCommand("echo hello", cmd.WithStandardStreams)
 //	c.Execute()
 func NewCommand(cmd string, options ...func(*Command)) *Command {
-	c := &Command{
-		Command:  cmd,
-		Timeout:  30 * time.Minute,
-		executed: false,
-		Env:      []string{},
-	}
+	return newCommand(cmd, nil, options)
+}
 
-	c.baseCommand = createBaseCommand(c)
-	c.StdoutWriter = io.MultiWriter(&c.stdout, &c.combined)
-	c.StderrWriter = io.MultiWriter(&c.stderr, &c.combined)
+// NewCommandArgs creates a new command which executes the program args[0]
+// with the remaining arguments directly instead of passing a command string to the shell.
+// This avoids quoting issues with arguments containing spaces or shell syntax.
+//
+// Example:
+//
+//	c := cmd.NewCommandArgs([]string{"ls", "-la", "/tmp/some dir"})
+//	c.Execute()
+func NewCommandArgs(args []string, options ...func(*Command)) *Command {
+	return newCommand(strings.Join(args, " "), args, options)
+}
+
+func newCommand(cmd string, args []string, options []func(*Command)) *Command {
+	c := &Command{}
+	c.init(cmd, args)
 
 	for _, o := range options {
 		o(c)
@@ -87,8 +136,37 @@ func NewCommand(cmd string, options ...func(*Command)) *Command {
 	return c
 }
 
+// init sets the defaults of a new command
+func (c *Command) init(cmd string, args []string) {
+	c.Command = cmd
+	c.Args = args
+	c.Timeout = 30 * time.Minute
+	c.executed = false
+	c.Env = []string{}
+
+	switch {
+	case args == nil:
+		c.baseCommand = createBaseCommand(c)
+	case len(args) == 0:
+		// exec.Cmd reports the missing program on Start
+		c.baseCommand = &exec.Cmd{}
+	default:
+		c.baseCommand = exec.Command(args[0], args[1:]...)
+	}
+	c.StdoutWriter = io.MultiWriter(&c.stdout, &c.combined)
+	c.StderrWriter = io.MultiWriter(&c.stderr, &c.combined)
+}
+
+// addOptionError stores an error of an option, options can not return errors
+// because they are applied by the constructor
+func (c *Command) addOptionError(err error) {
+	c.optionErr = errors.Join(c.optionErr, err)
+}
+
 // WithCustomBaseCommand allows the OS specific generated baseCommand
 // to be overridden by an *os/exec.Cmd.
+// For commands created with NewCommandArgs the args are appended
+// instead of the command string.
 //
 // Example:
 //
@@ -99,11 +177,30 @@ func NewCommand(cmd string, options ...func(*Command)) *Command {
 //	c.Execute()
 func WithCustomBaseCommand(baseCommand *exec.Cmd) func(c *Command) {
 	return func(c *Command) {
-		baseCommand.Args = append(baseCommand.Args, c.Command)
+		if c.Args != nil {
+			baseCommand.Args = append(baseCommand.Args, c.Args...)
+		} else {
+			baseCommand.Args = append(baseCommand.Args, c.Command)
+		}
 		c.baseCommand = baseCommand
 	}
 }
 
+// WithSysProcAttr modifies the OS specific attributes of the process
+// All modifications are applied in order on execution, so options which set
+// different attributes can be combined, e.g. WithUser and WithProcessGroup on linux.
+//
+// Example:
+//
+//	c := cmd.NewCommand("echo hello", cmd.WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+//	    attr.Setsid = true
+//	}))
+func WithSysProcAttr(fn func(attr *syscall.SysProcAttr)) func(c *Command) {
+	return func(c *Command) {
+		c.sysProcAttrs = append(c.sysProcAttrs, fn)
+	}
+}
+
 // WithStandardStreams is used as an option by the NewCommand constructor function and writes the output streams
 // to stderr and stdout of the operating system
 //
@@ -132,6 +229,31 @@ func WithCustomStderr(writers ...io.Writer) func(c *Command) {
 	}
 }
 
+// WithStdin sets the reader from which the command reads its stdin
+//
+// Example:
+//
+//	cmd.NewCommand("cat", cmd.WithStdin(strings.NewReader("hello")))
+func WithStdin(r io.Reader) func(c *Command) {
+	return func(c *Command) {
+		c.StdinReader = r
+	}
+}
+
+// WithExpectedExitCodes sets the exit codes which are treated as success,
+// by default only 0 is a successful exit code
+//
+// Example:
+//
+//	c := cmd.NewCommand("grep hello file.txt", cmd.WithExpectedExitCodes(0, 1))
+//	c.Execute()
+//	c.Succeeded()
+func WithExpectedExitCodes(codes ...int) func(c *Command) {
+	return func(c *Command) {
+		c.expectedExitCodes = codes
+	}
+}
+
 // WithTimeout sets the timeout of the command
 //
 // Example:
@@ -152,6 +274,9 @@ func WithoutTimeout(c *Command) {
 func WithWorkingDir(dir string) func(c *Command) {
 	return func(c *Command) {
 		c.WorkingDir = dir
+		if dir != "" && c.tempDir != nil {
+			c.addOptionError(errTempDirWithWorkingDir)
+		}
 	}
 }
 
@@ -160,6 +285,7 @@ func WithWorkingDir(dir string) func(c *Command) {
 func WithInheritedEnvironment(env EnvVars) func(c *Command) {
 	return func(c *Command) {
 		c.Env = os.Environ()
+		c.Environment().deduplicate()
 
 		// Set custom variables
 		fn := WithEnvironmentVariables(env)
@@ -168,19 +294,59 @@ func WithInheritedEnvironment(env EnvVars) func(c *Command) {
 }
 
 // WithEnvironmentVariables sets environment variables for the executed command
+// The variables are added in the alphabetical order of their keys.
 func WithEnvironmentVariables(env EnvVars) func(c *Command) {
 	return func(c *Command) {
-		for key, value := range env {
-			c.AddEnv(key, value)
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			c.AddEnv(key, env[key])
+		}
+	}
+}
+
+// WithoutEnv removes environment variables from the command,
+// e.g. variables inherited by WithInheritedEnvironment
+//
+// Example:
+//
+//	c := cmd.NewCommand(
+//	    "./build.sh",
+//	    cmd.WithInheritedEnvironment(nil),
+//	    cmd.WithoutEnv("AWS_SECRET_ACCESS_KEY", "GITHUB_TOKEN"),
+//	)
+func WithoutEnv(keys ...string) func(c *Command) {
+	return func(c *Command) {
+		for _, key := range keys {
+			c.Environment().Unset(key)
 		}
 	}
 }
 
 // AddEnv adds an environment variable to the command
-// If a variable gets passed like ${VAR_NAME} the env variable will be read out by the current shell
+// If the variable already exists its value is replaced.
+// If a variable gets passed like ${VAR_NAME} the env variable will be read out by the current shell,
+// see WithEnvExpansion to change this behaviour.
 func (c *Command) AddEnv(key, value string) {
-	value = os.ExpandEnv(value)
-	c.Env = append(c.Env, fmt.Sprintf("%s=%s", key, value))
+	value, err := c.expandEnv(key, value)
+	if err != nil {
+		c.addOptionError(err)
+	}
+	c.Environment().Set(key, value)
+}
+
+// AddEnvLiteral adds an environment variable to the command without expanding variables in its value
+func (c *Command) AddEnvLiteral(key, value string) {
+	c.Environment().Set(key, value)
+}
+
+// Environment returns the env of the command to set, get and unset variables
+func (c *Command) Environment() *Environment {
+	return (*Environment)(&c.Env)
 }
 
 // Stdout returns the output to stdout
@@ -207,6 +373,32 @@ func (c *Command) ExitCode() int {
 	return c.exitCode
 }
 
+// Signal returns the signal which terminated the command, e.g. SIGXCPU if it
+// exceeded its cpu time limit. The exit code is -1 in this case.
+// It returns 0 if the command exited on its own.
+func (c *Command) Signal() syscall.Signal {
+	c.isExecuted("Signal")
+	return c.signal
+}
+
+// Succeeded returns if the command exited with an expected exit code
+func (c *Command) Succeeded() bool {
+	c.isExecuted("Succeeded")
+	return c.isExpectedExitCode(c.exitCode)
+}
+
+func (c *Command) isExpectedExitCode(code int) bool {
+	if len(c.expectedExitCodes) == 0 {
+		return code == 0
+	}
+	for _, expected := range c.expectedExitCodes {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
+
 // Executed returns if the command was already executed
 func (c *Command) Executed() bool {
 	return c.executed
@@ -219,13 +411,83 @@ func (c *Command) isExecuted(property string) {
 }
 
 // ExecuteContext runs Execute but with Context
+// The command is executed through the middleware added with WithMiddleware.
 func (c *Command) ExecuteContext(ctx context.Context) error {
+	return c.executor()(ctx, c)
+}
+
+// processEnv returns the env of the process of the current execution
+func (c *Command) processEnv() []string {
+	env := Environment(c.Env)
+	if env == nil {
+		if c.traceParent == "" {
+			return nil
+		}
+		// The process would not inherit the env of the current process otherwise
+		env = os.Environ()
+	}
+
+	// Command.Env may be assigned directly and contain duplicated keys
+	env = append(Environment{}, env...)
+	env.deduplicate()
+	if c.traceParent != "" {
+		env.Set(TraceParentEnv, c.traceParent)
+	}
+	return env
+}
+
+func (c *Command) execute(ctx context.Context) (err error) {
+	if c.optionErr != nil {
+		return c.optionErr
+	}
+
+	workingDir := c.WorkingDir
+	if c.tempDir != nil {
+		dir, createErr := c.tempDir.create()
+		if createErr != nil {
+			return createErr
+		}
+		workingDir = dir
+		defer func() {
+			failed := err != nil || !c.executed || !c.isExpectedExitCode(c.exitCode)
+			err = errors.Join(err, c.tempDir.remove(failed))
+		}()
+	}
+
+	if err := c.beforeStart(ctx); err != nil {
+		return err
+	}
+
+	if c.preflight {
+		if err := c.validate(workingDir); err != nil {
+			return err
+		}
+	} else if c.chroot != "" {
+		if err := c.validateChroot(); err != nil {
+			return err
+		}
+	}
+
 	cmd := c.baseCommand
-	cmd.Env = c.Env
+	cmd.Env = c.processEnv()
 	cmd.Dir = c.Dir
 	cmd.Stdout = c.StdoutWriter
 	cmd.Stderr = c.StderrWriter
-	cmd.Dir = c.WorkingDir
+	cmd.Stdin = c.StdinReader
+	cmd.Dir = workingDir
+	if c.chroot != "" {
+		// The process would keep a working dir outside of the new root otherwise
+		cmd.Dir = path.Join("/", workingDir)
+	}
+	if len(c.sysProcAttrs) > 0 {
+		// Keep the attributes of a custom base command
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		for _, fn := range c.sysProcAttrs {
+			fn(cmd.SysProcAttr)
+		}
+	}
 
 	// Respect legacy timer setting only if timeout was set > 0
 	// and context does not have a deadline
@@ -236,28 +498,47 @@ func (c *Command) ExecuteContext(ctx context.Context) error {
 		ctx = subCtx
 	}
 
-	err := cmd.Start()
-	if err != nil {
+	if c.dryRun != nil {
+		return c.dryRun.record(c)
+	}
+
+	if c.cgroup != nil {
+		if err := c.cgroup.create(cmd); err != nil {
+			return err
+		}
+		defer func() {
+			err = errors.Join(err, c.cgroup.remove())
+		}()
+	}
+
+	if err := startProcess(c, cmd); err != nil {
+		c.logStartError(err)
 		return err
 	}
 
+	pid := cmd.Process.Pid
 	done := make(chan error, 1)
 	go func() { done <- cmd.Wait() }()
 	c.executed = true
+	c.logStart(pid)
+	c.afterStart(pid)
 
 	select {
 	case <-ctx.Done():
 		if err := cmd.Process.Kill(); err != nil {
-			return fmt.Errorf("timeout occurred and can not kill process with pid %v", cmd.Process.Pid)
+			c.logKillError(pid, err)
+			return fmt.Errorf("timeout occurred and can not kill process with pid %v", pid)
 		}
 
 		err := ctx.Err()
-		if c.Timeout > 0 && !hasDeadline {
+		c.logCanceled(pid, err)
+		if c.Timeout > 0 && !hasDeadline && errors.Is(err, context.DeadlineExceeded) {
 			err = fmt.Errorf("command timed out after %v", c.Timeout)
 		}
 		return err
 	case err := <-done:
 		c.getExitCode(err)
+		c.logExit(pid)
 	}
 
 	return nil
@@ -273,6 +554,9 @@ func (c *Command) getExitCode(err error) {
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
 			c.exitCode = status.ExitStatus()
+			if status.Signaled() {
+				c.signal = status.Signal()
+			}
 		}
 	}
 }
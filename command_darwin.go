@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"os/exec"
 	"syscall"
 )
@@ -19,9 +20,21 @@ func createBaseCommand(c *Command) *exec.Cmd {
 //	c := NewCommand("echo hello", cred)
 //	c.Execute()
 func WithUser(credential syscall.Credential) func(c *Command) {
-	return func(c *Command) {
-		c.baseCommand.SysProcAttr = &syscall.SysProcAttr{
-			Credential: &credential,
-		}
+	return WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+		attr.Credential = &credential
+	})
+}
+
+// processUser describes the credential the command runs as
+func processUser(cmd *exec.Cmd) string {
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.Credential == nil {
+		return ""
 	}
+	cred := cmd.SysProcAttr.Credential
+	return fmt.Sprintf("uid=%d gid=%d", cred.Uid, cred.Gid)
+}
+
+// startProcess starts the command
+func startProcess(c *Command, cmd *exec.Cmd) error {
+	return cmd.Start()
 }
@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
 	"syscall"
 )
 
@@ -19,9 +24,204 @@ func createBaseCommand(c *Command) *exec.Cmd {
 //	c := NewCommand("echo hello", WithUser(cred))
 //	c.Execute()
 func WithUser(credential syscall.Credential) func(c *Command) {
+	return WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+		attr.Credential = &credential
+	})
+}
+
+// startProcess starts the command
+// Commands which inherit attributes of the starting thread are started on
+// a thread with these attributes, see onThread.
+func startProcess(c *Command, cmd *exec.Cmd) error {
+	attrs := threadAttrs{
+		noNewPrivs: c.noNewPrivs,
+		clearCaps:  c.restrictCaps,
+		noRoot:     c.restrictCaps && runsAsRoot(cmd.SysProcAttr),
+		ioPriority: c.ioPriority,
+	}
+	if c.nice != nil {
+		attrs.setNice, attrs.nice = true, *c.nice
+	}
+	if attrs != (threadAttrs{}) {
+		return onThread(attrs, func() error {
+			return startTraced(c, cmd)
+		})
+	}
+	return startTraced(c, cmd)
+}
+
+// startTraced starts the command
+// If the command has beforeRun functions the process is traced, which stops it
+// after exec before the first instruction of the new program. The functions are
+// applied to the stopped process, which is detached afterwards.
+func startTraced(c *Command, cmd *exec.Cmd) error {
+	if len(c.beforeRun) == 0 {
+		return cmd.Start()
+	}
+
+	// Only the thread which started a traced process can wait for and detach it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Ptrace = true
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, syscall.EPERM) {
+			return fmt.Errorf("can not trace command to set resource limits, ptrace may not be permitted: %w", err)
+		}
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	err := waitForExec(pid)
+	for _, fn := range c.beforeRun {
+		if err != nil {
+			break
+		}
+		err = fn(pid)
+	}
+	if err == nil {
+		err = syscall.PtraceDetach(pid)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	return nil
+}
+
+// waitForExec waits until the traced process is stopped after exec
+func waitForExec(pid int) error {
+	var status syscall.WaitStatus
+	for {
+		_, err := syscall.Wait4(pid, &status, syscall.WALL, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("can not wait for process with pid %d: %w", pid, err)
+		}
+		if !status.Stopped() {
+			return fmt.Errorf("process with pid %d exited before it was started", pid)
+		}
+		return nil
+	}
+}
+
+// processUser describes the credential the command runs as
+func processUser(cmd *exec.Cmd) string {
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.Credential == nil {
+		return ""
+	}
+	cred := cmd.SysProcAttr.Credential
+	return fmt.Sprintf("uid=%d gid=%d", cred.Uid, cred.Gid)
+}
+
+// WithProcessGroup starts the command in a new process group
+// whose id is the pid of the command.
+func WithProcessGroup(c *Command) {
+	WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+		attr.Setpgid = true
+		attr.Pgid = 0
+	})(c)
+}
+
+// WithParentDeathSignal sends the signal to the command if the current process dies
+// Processes started by the command do not receive the signal, use WithCgroup
+// or WithNamespaces(PIDNamespace) to kill them too.
+//
+// The kernel sends the signal when the thread which started the command exits,
+// not the process. Go only terminates a thread if a goroutine exits while it is
+// locked with runtime.LockOSThread, so do not execute commands from goroutines
+// which lock their thread and exit without unlocking it.
+//
+// The signal is kept if the command runs as another user, see WithUser, because it is set
+// after the credentials were changed. Executing a set-user-ID binary, e.g. sudo,
+// clears the signal.
+//
+// Example:
+//
+//	c := cmd.NewCommand("./worker", cmd.WithParentDeathSignal(syscall.SIGKILL))
+//	c.Execute()
+func WithParentDeathSignal(sig syscall.Signal) func(c *Command) {
+	return WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+		attr.Pdeathsig = sig
+	})
+}
+
+// UserConfig configures WithUsername
+type UserConfig struct {
+	Username string
+	// SetEnv sets HOME, USER and LOGNAME of the command to the values of the user
+	SetEnv bool
+}
+
+// WithUsername allows the command to be run as the user with the given name
+// The uid, gid and supplementary groups are looked up with os/user.
+// If the user does not exist, Execute and ExecuteContext return an error
+// before the command is started.
+//
+// Example:
+//
+//	c := cmd.NewCommand("make", cmd.WithUsername("builder", cmd.WithUserEnv))
+//	c.Execute()
+func WithUsername(username string, options ...func(*UserConfig)) func(c *Command) {
 	return func(c *Command) {
-		c.baseCommand.SysProcAttr = &syscall.SysProcAttr{
-			Credential: &credential,
+		conf := &UserConfig{Username: username}
+		for _, o := range options {
+			o(conf)
+		}
+
+		u, credential, err := lookupCredential(conf.Username)
+		if err != nil {
+			c.addOptionError(err)
+			return
 		}
+
+		WithUser(*credential)(c)
+		if conf.SetEnv {
+			c.Environment().Set("HOME", u.HomeDir)
+			c.Environment().Set("USER", u.Username)
+			c.Environment().Set("LOGNAME", u.Username)
+		}
+	}
+}
+
+// WithUserEnv sets HOME, USER and LOGNAME of the command, see WithUsername
+func WithUserEnv(conf *UserConfig) {
+	conf.SetEnv = true
+}
+
+func lookupCredential(username string) (*user.User, *syscall.Credential, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not run command as user %q: %w", username, err)
 	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid uid %q of user %q: %w", u.Uid, username, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid gid %q of user %q: %w", u.Gid, username, err)
+	}
+
+	groupIds, err := u.GroupIds()
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not read groups of user %q: %w", username, err)
+	}
+	groups := make([]uint32, 0, len(groupIds))
+	for _, g := range groupIds {
+		id, err := strconv.ParseUint(g, 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid group id %q of user %q: %w", g, username, err)
+		}
+		groups = append(groups, uint32(id))
+	}
+
+	return u, &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}, nil
 }
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"os"
 	"os/exec"
+	"os/user"
+	"strconv"
 	"strings"
 	"syscall"
 	"testing"
@@ -168,3 +171,224 @@ func TestCommand_WithUser(t *testing.T) {
 	err := cmd.Execute()
 	assert.Error(t, err)
 }
+
+func TestCommand_WithStdin(t *testing.T) {
+	c := NewCommand("cat", WithStdin(strings.NewReader("from stdin")))
+
+	err := c.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "from stdin", c.Stdout())
+}
+
+func TestCommand_NewCommandArgs(t *testing.T) {
+	c := NewCommandArgs([]string{"echo", "$HOME", "a  b"})
+
+	err := c.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "echo $HOME a  b", c.Command)
+	assert.Equal(t, "$HOME a  b\n", c.Stdout())
+}
+
+func TestCommand_NewCommandArgsWithoutArgs(t *testing.T) {
+	c := NewCommandArgs([]string{})
+
+	err := c.Execute()
+
+	assert.EqualError(t, err, "exec: no command")
+}
+
+func TestCommand_NewCommandArgsWithCustomBaseCommand(t *testing.T) {
+	c := NewCommandArgs(
+		[]string{"echo", "hello"},
+		WithCustomBaseCommand(exec.Command("/usr/bin/env", "-i")),
+	)
+
+	err := c.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hello\n", c.Stdout())
+}
+
+func TestCommand_WithDuplicatedEnv(t *testing.T) {
+	c := NewCommand("echo $KEY", WithDryRun(&DryRun{}))
+	c.Env = []string{"KEY=first", "OTHER=value", "KEY=last"}
+
+	err := c.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"KEY=last", "OTHER=value"}, c.baseCommand.Env)
+}
+
+func TestCommand_SysProcAttrOptionsCombine(t *testing.T) {
+	cred := syscall.Credential{Uid: 1000, Gid: 1000}
+	setsid := WithSysProcAttr(func(attr *syscall.SysProcAttr) { attr.Setsid = true })
+
+	combinations := [][]func(*Command){
+		{WithUser(cred), WithProcessGroup, setsid},
+		{WithProcessGroup, setsid, WithUser(cred)},
+		{setsid, WithUser(cred), WithProcessGroup},
+	}
+
+	for _, options := range combinations {
+		c := NewCommand("echo hello", append(options, WithDryRun(&DryRun{}))...)
+		err := c.Execute()
+
+		require.NoError(t, err)
+		attr := c.baseCommand.SysProcAttr
+		assert.Equal(t, uint32(1000), attr.Credential.Uid)
+		assert.True(t, attr.Setpgid)
+		assert.True(t, attr.Setsid)
+	}
+}
+
+func TestCommand_SysProcAttrKeepsCustomBaseCommandAttributes(t *testing.T) {
+	base := exec.Command("/bin/sh", "-c")
+	base.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	c := NewCommand("echo hello", WithProcessGroup, WithCustomBaseCommand(base), WithDryRun(&DryRun{}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.True(t, c.baseCommand.SysProcAttr.Setsid)
+	assert.True(t, c.baseCommand.SysProcAttr.Setpgid)
+}
+
+func TestCommand_WithProcessGroup(t *testing.T) {
+	// The fifth field of /proc/<pid>/stat is the process group id
+	c := NewCommand("cut -d' ' -f5 /proc/$$/stat; echo $$", WithProcessGroup)
+	err := c.Execute()
+
+	require.NoError(t, err)
+	lines := strings.Fields(c.Stdout())
+	require.Len(t, lines, 2)
+	assert.Equal(t, lines[1], lines[0])
+}
+
+func TestCommand_WithUsername(t *testing.T) {
+	c := NewCommand("id", WithUsername("nobody"), WithDryRun(&DryRun{}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	cred := c.baseCommand.SysProcAttr.Credential
+	assert.Equal(t, uint32(65534), cred.Uid)
+	assert.Contains(t, cred.Groups, cred.Gid)
+	assert.Equal(t, []string{}, c.Env)
+}
+
+func TestCommand_WithUsernameWithUserEnv(t *testing.T) {
+	c := NewCommand(
+		"echo $HOME",
+		WithEnvironmentVariables(EnvVars{"HOME": "/root", "OTHER": "value"}),
+		WithUsername("nobody", WithUserEnv),
+	)
+
+	assert.Equal(t, []string{"HOME=/nonexistent", "OTHER=value", "USER=nobody", "LOGNAME=nobody"}, c.Env)
+}
+
+func TestCommand_WithUsernameUnknownUser(t *testing.T) {
+	c := NewCommand("id", WithUsername("cmd-user-does-not-exist"))
+	err := c.Execute()
+
+	var unknownErr user.UnknownUserError
+	assert.ErrorAs(t, err, &unknownErr)
+	assert.EqualError(t, err, `can not run command as user "cmd-user-does-not-exist": user: unknown user cmd-user-does-not-exist`)
+	assert.False(t, c.Executed())
+}
+
+func TestCommand_WithUsernameExecute(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("running commands as a different user requires root")
+	}
+
+	c := NewCommand("id -un; echo $USER", WithUsername("nobody", WithUserEnv))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "nobody\nnobody\n", c.Stdout())
+}
+
+// TestParentDeathSignalHelper is executed as a separate process by startParentDeathSignalHelper
+// It prints the pid of a command started with the options of its mode and blocks.
+func TestParentDeathSignalHelper(t *testing.T) {
+	mode := os.Getenv("CMD_TEST_PARENT_DEATH_SIGNAL")
+	if mode == "" {
+		t.Skip("helper process of TestCommand_WithParentDeathSignal")
+	}
+
+	options := []func(*Command){WithCustomStdout(os.Stdout)}
+	switch mode {
+	case "signal":
+		options = append(options, WithParentDeathSignal(syscall.SIGKILL))
+	case "user":
+		options = append(options, WithParentDeathSignal(syscall.SIGKILL), WithUser(syscall.Credential{Uid: 65534, Gid: 65534}))
+	case "limits":
+		// The command is started on a locked thread which is unlocked after the start
+		options = append(options, WithParentDeathSignal(syscall.SIGKILL), WithResourceLimits(Limits{OpenFiles: 64}))
+	}
+
+	NewCommand("echo $$; exec sleep 60", options...).Execute()
+}
+
+// startParentDeathSignalHelper starts TestParentDeathSignalHelper and returns the pid of its command
+func startParentDeathSignalHelper(t *testing.T, mode string) (*exec.Cmd, int) {
+	helper := exec.Command(os.Args[0], "-test.run=^TestParentDeathSignalHelper$")
+	helper.Env = append(os.Environ(), "CMD_TEST_PARENT_DEATH_SIGNAL="+mode)
+	stdout, err := helper.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, helper.Start())
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if pid, err := strconv.Atoi(scanner.Text()); err == nil {
+			return helper, pid
+		}
+	}
+	helper.Process.Kill()
+	helper.Wait()
+	t.Fatal("helper process did not print the pid of its command")
+	return nil, 0
+}
+
+// isRunning reports if a process is running, processes which were killed
+// and not reaped yet are not running
+func isRunning(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return fields[0] != "Z"
+}
+
+func TestCommand_WithParentDeathSignal(t *testing.T) {
+	modes := []string{"signal", "user", "limits"}
+
+	for _, mode := range modes {
+		t.Run(mode, func(t *testing.T) {
+			if mode == "user" && os.Geteuid() != 0 {
+				t.Skip("running commands as a different user requires root")
+			}
+
+			helper, pid := startParentDeathSignalHelper(t, mode)
+			require.True(t, isRunning(pid))
+
+			require.NoError(t, helper.Process.Kill())
+			helper.Wait()
+
+			assert.Eventually(t, func() bool { return !isRunning(pid) }, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestCommand_WithoutParentDeathSignal(t *testing.T) {
+	helper, pid := startParentDeathSignalHelper(t, "none")
+	defer syscall.Kill(pid, syscall.SIGKILL)
+
+	require.NoError(t, helper.Process.Kill())
+	helper.Wait()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, isRunning(pid))
+}
@@ -130,3 +130,19 @@ func TestCommand_SetOptions(t *testing.T) {
 	assert.Equal(t, time.Duration(1000000000), c.Timeout)
 	assertEqualWithLineBreak(t, "test", writer.String())
 }
+
+func TestCommand_WithExpectedExitCodes(t *testing.T) {
+	c := NewCommand("exit 1", WithExpectedExitCodes(0, 1))
+	err := c.Execute()
+
+	assert.Nil(t, err)
+	assert.True(t, c.Succeeded())
+
+	c = NewCommand("exit 2", WithExpectedExitCodes(0, 1))
+	_ = c.Execute()
+	assert.False(t, c.Succeeded())
+
+	c = NewCommand("exit 0")
+	_ = c.Execute()
+	assert.True(t, c.Succeeded())
+}
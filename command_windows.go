@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"os/exec"
 	"syscall"
 )
@@ -19,9 +20,20 @@ func createBaseCommand(c *Command) *exec.Cmd {
 //	c := NewCommand("echo hello", WithUser(token))
 //	c.Execute()
 func WithUser(token syscall.Token) func(c *Command) {
-	return func(c *Command) {
-		c.baseCommand.SysProcAttr = &syscall.SysProcAttr{
-			Token: token,
-		}
+	return WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+		attr.Token = token
+	})
+}
+
+// processUser describes the token the command runs with
+func processUser(cmd *exec.Cmd) string {
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.Token == 0 {
+		return ""
 	}
+	return fmt.Sprintf("token=%d", cmd.SysProcAttr.Token)
+}
+
+// startProcess starts the command
+func startProcess(c *Command, cmd *exec.Cmd) error {
+	return cmd.Start()
 }
@@ -44,3 +44,17 @@ func TestCommand_WithUser(t *testing.T) {
 	err := cmd.Execute()
 	assert.Error(t, err)
 }
+
+func TestEnvironment_CaseInsensitiveKeys(t *testing.T) {
+	env := Environment{"Path=C:\\Windows", "A=1"}
+
+	env.Set("PATH", "C:\\bin")
+	assert.Equal(t, Environment{"PATH=C:\\bin", "A=1"}, env)
+
+	value, ok := env.Get("path")
+	assert.True(t, ok)
+	assert.Equal(t, "C:\\bin", value)
+
+	env.Unset("pAtH")
+	assert.Equal(t, Environment{"A=1"}, env)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"io"
+	"sync"
+)
+
+// DryRun records commands instead of executing them
+// Every command returns the configured exit code and writes
+// the configured output to its writers.
+type DryRun struct {
+	ExitCode    int
+	Stdout      string
+	Stderr      string
+	mu          sync.Mutex
+	invocations []Invocation
+}
+
+// Invocation describes a command as it would have been started
+type Invocation struct {
+	// Path is the resolved path of the executable
+	Path string
+	// Args contains the executable and all of its arguments,
+	// e.g. ["/bin/sh", "-c", "echo hello"]
+	Args []string
+	Env  []string
+	Dir  string
+	// User describes the user the command runs as, empty for the current user
+	User string
+}
+
+// WithDryRun skips the execution of the command and records the invocation instead
+//
+// Example:
+//
+//	dryRun := &cmd.DryRun{Stdout: "deployed\n"}
+//	c := cmd.NewCommand("./deploy.sh", cmd.WithDryRun(dryRun))
+//	c.Execute()
+//	fmt.Println(dryRun.Invocations()[0].Args)
+func WithDryRun(d *DryRun) func(c *Command) {
+	return func(c *Command) {
+		c.dryRun = d
+	}
+}
+
+// Invocations returns all recorded invocations in the order they were executed
+func (d *DryRun) Invocations() []Invocation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Invocation(nil), d.invocations...)
+}
+
+func (d *DryRun) record(c *Command) error {
+	cmd := c.baseCommand
+	d.mu.Lock()
+	d.invocations = append(d.invocations, Invocation{
+		Path: cmd.Path,
+		Args: append([]string(nil), cmd.Args...),
+		Env:  append([]string(nil), cmd.Env...),
+		Dir:  cmd.Dir,
+		User: processUser(cmd),
+	})
+	d.mu.Unlock()
+
+	if _, err := io.WriteString(c.StdoutWriter, d.Stdout); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(c.StderrWriter, d.Stderr); err != nil {
+		return err
+	}
+	c.exitCode = d.ExitCode
+	c.executed = true
+	return nil
+}
@@ -0,0 +1,70 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_WithDryRun(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "created")
+	dryRun := &DryRun{ExitCode: 3, Stdout: "out\n", Stderr: "err\n"}
+
+	c := NewCommand(
+		"touch "+file,
+		WithDryRun(dryRun),
+		WithWorkingDir(dir),
+		WithEnvironmentVariables(EnvVars{"KEY": "value"}),
+	)
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.NoFileExists(t, file)
+	assert.True(t, c.Executed())
+	assert.Equal(t, 3, c.ExitCode())
+	assert.Equal(t, "out\n", c.Stdout())
+	assert.Equal(t, "err\n", c.Stderr())
+	assert.Equal(t, "out\nerr\n", c.Combined())
+
+	assert.Equal(t, []Invocation{{
+		Path: "/bin/sh",
+		Args: []string{"/bin/sh", "-c", "touch " + file},
+		Env:  []string{"KEY=value"},
+		Dir:  dir,
+	}}, dryRun.Invocations())
+}
+
+func TestCommand_WithDryRunRecordsAllCommands(t *testing.T) {
+	dryRun := &DryRun{}
+
+	result, err := NewSequence([]*Command{
+		NewCommandArgs([]string{"ls", "-la"}, WithDryRun(dryRun)),
+		NewCommand("exit 1", WithDryRun(dryRun), WithUser(syscall.Credential{Uid: 1000, Gid: 100})),
+	}).Run(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, result.Failed())
+
+	invocations := dryRun.Invocations()
+	require.Len(t, invocations, 2)
+	assert.Equal(t, []string{"ls", "-la"}, invocations[0].Args)
+	assert.True(t, filepath.IsAbs(invocations[0].Path))
+	assert.Equal(t, "", invocations[0].User)
+	assert.Equal(t, "uid=1000 gid=100", invocations[1].User)
+}
+
+func TestCommand_WithDryRunWithTimeout(t *testing.T) {
+	c := NewCommand("sleep 10", WithDryRun(&DryRun{}), WithTimeout(1))
+
+	err := c.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", c.Stdout())
+}
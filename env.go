@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// Environment is a list of environment variables in the KEY=VALUE format
+// Every key is contained only once, setting an existing key replaces its
+// value in place so the order of the variables stays deterministic.
+// Keys are case insensitive on windows, like in os/exec.
+//
+// Example:
+//
+//	env := cmd.Environment{"HOME=/root"}
+//	env.Set("PATH", "/usr/bin")
+//	env.Set("HOME", "/home/build")
+//	env.Unset("PATH")
+//	// env is ["HOME=/home/build"]
+type Environment []string
+
+// Set sets the value of a variable
+// An existing variable keeps its position, new variables are appended.
+func (e *Environment) Set(key, value string) {
+	entry := key + "=" + value
+	i := e.index(key)
+	if i < 0 {
+		*e = append(*e, entry)
+		return
+	}
+
+	(*e)[i] = entry
+	e.remove(key, i+1)
+}
+
+// Unset removes a variable
+func (e *Environment) Unset(key string) {
+	e.remove(key, 0)
+}
+
+// Get returns the value of a variable and if it is set
+// If a key was added more than once, e.g. by assigning Command.Env directly,
+// the last value wins.
+func (e Environment) Get(key string) (string, bool) {
+	for i := len(e) - 1; i >= 0; i-- {
+		if k, v := splitEnv(e[i]); sameEnvKey(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// index returns the index of the first occurrence of a variable
+func (e Environment) index(key string) int {
+	for i, v := range e {
+		if k, _ := splitEnv(v); sameEnvKey(k, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// remove removes all occurrences of a variable starting at index from
+func (e *Environment) remove(key string, from int) {
+	vars := (*e)[:from]
+	for _, v := range (*e)[from:] {
+		if k, _ := splitEnv(v); !sameEnvKey(k, key) {
+			vars = append(vars, v)
+		}
+	}
+	*e = vars
+}
+
+// splitEnv splits a KEY=VALUE entry, a leading = belongs to the key like in os/exec,
+// e.g. windows stores the working directories of drives as =C:=C:\dir
+func splitEnv(entry string) (key, value string) {
+	if i := strings.Index(entry[min(len(entry), 1):], "="); i >= 0 {
+		return entry[:i+1], entry[i+2:]
+	}
+	return entry, ""
+}
+
+func sameEnvKey(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// deduplicate removes duplicated keys, the last value of a key
+// is kept at the position of its first occurrence
+func (e *Environment) deduplicate() {
+	for i := 0; i < len(*e); i++ {
+		key, _ := splitEnv((*e)[i])
+		if value, ok := e.Get(key); ok {
+			e.Set(key, value)
+		}
+	}
+}
+
+// EnvExpansion defines how AddEnv expands variables like $VAR or ${VAR} in values
+type EnvExpansion int
+
+const (
+	// ExpandFromProcess expands variables from the env of the current process, this is the default
+	ExpandFromProcess EnvExpansion = iota
+	// ExpandFromCommand expands variables from the env of the command itself,
+	// e.g. variables which were added before
+	ExpandFromCommand
+	// NoExpansion adds all values literally
+	NoExpansion
+)
+
+// UndefinedEnvError is returned in strict mode if a value references an undefined variable
+type UndefinedEnvError struct {
+	Key       string
+	Reference string
+}
+
+func (e *UndefinedEnvError) Error() string {
+	return fmt.Sprintf("env variable %s references undefined variable %s", e.Key, e.Reference)
+}
+
+// WithEnvExpansion sets how AddEnv expands variables in values
+// It must be passed before the options which add env variables.
+//
+// Example:
+//
+//	c := cmd.NewCommand(
+//	    "./deploy.sh",
+//	    cmd.WithEnvExpansion(cmd.ExpandFromCommand),
+//	    cmd.WithEnvironmentVariables(cmd.EnvVars{"HOST": "example.com"}),
+//	    func(c *cmd.Command) { c.AddEnv("URL", "https://${HOST}/api") },
+//	)
+func WithEnvExpansion(e EnvExpansion) func(c *Command) {
+	return func(c *Command) {
+		c.envExpansion = e
+	}
+}
+
+// WithStrictEnvExpansion makes references to undefined variables an error
+// The error is returned by Execute and ExecuteContext before the command is started.
+// It must be passed before the options which add env variables.
+func WithStrictEnvExpansion(c *Command) {
+	c.strictEnv = true
+}
+
+func (c *Command) expandEnv(key, value string) (string, error) {
+	return c.expandEnvWith(key, value, nil)
+}
+
+// expandEnvWith expands like expandEnv, but looks up variables in vars first
+func (c *Command) expandEnvWith(key, value string, vars map[string]string) (string, error) {
+	var lookup func(string) (string, bool)
+	switch c.envExpansion {
+	case NoExpansion:
+		return value, nil
+	case ExpandFromCommand:
+		lookup = c.Environment().Get
+	default:
+		lookup = os.LookupEnv
+	}
+
+	var undefined []string
+	value = os.Expand(value, func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		v, ok := lookup(name)
+		if !ok {
+			undefined = append(undefined, name)
+		}
+		return v
+	})
+
+	if c.strictEnv && len(undefined) > 0 {
+		return value, &UndefinedEnvError{Key: key, Reference: strings.Join(undefined, ", ")}
+	}
+	return value, nil
+}
+
+// EnvFilter selects env variables by their keys with glob patterns like AWS_*
+// Patterns use the syntax of path.Match and are case sensitive.
+type EnvFilter struct {
+	// Allow contains the patterns of variables to keep, if empty all variables are allowed
+	Allow []string
+	// Deny contains the patterns of variables to remove, deny takes precedence over allow
+	Deny []string
+}
+
+// WithInheritedEnvironmentFiltered uses the env from the current process which matches
+// the filter and allows to add more variables, which are not filtered.
+// Invalid patterns are returned by Execute and ExecuteContext before the command is started.
+//
+// Example:
+//
+//	c := cmd.NewCommand("make", cmd.WithInheritedEnvironmentFiltered(
+//	    cmd.EnvFilter{Deny: []string{"AWS_*", "*_TOKEN"}},
+//	    cmd.EnvVars{"CI": "true"},
+//	))
+func WithInheritedEnvironmentFiltered(filter EnvFilter, env EnvVars) func(c *Command) {
+	return func(c *Command) {
+		inherited := Environment{}
+		for _, e := range os.Environ() {
+			key, value := splitEnv(e)
+			ok, err := filter.matches(key)
+			if err != nil {
+				c.addOptionError(err)
+				return
+			}
+			if ok {
+				inherited.Set(key, value)
+			}
+		}
+		c.Env = inherited
+
+		// Set custom variables
+		fn := WithEnvironmentVariables(env)
+		fn(c)
+	}
+}
+
+// WithMinimalEnvironment only inherits the variables of the current process which are
+// required by most commands, e.g. PATH, HOME, LANG, TERM and USER on linux.
+// It allows to add more variables for hermetic runs.
+func WithMinimalEnvironment(env EnvVars) func(c *Command) {
+	return WithInheritedEnvironmentFiltered(EnvFilter{Allow: minimalEnvironment}, env)
+}
+
+func (f EnvFilter) matches(key string) (bool, error) {
+	denied, err := matchesAny(f.Deny, key)
+	if err != nil || denied {
+		return false, err
+	}
+	if len(f.Allow) == 0 {
+		return true, nil
+	}
+	return matchesAny(f.Allow, key)
+}
+
+func matchesAny(patterns []string, key string) (bool, error) {
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, key)
+		if err != nil {
+			return false, fmt.Errorf("invalid env filter pattern %q: %w", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
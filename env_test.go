@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironment_Set(t *testing.T) {
+	env := Environment{"A=1", "B=2"}
+
+	env.Set("C", "3")
+	env.Set("A", "overwritten")
+
+	assert.Equal(t, Environment{"A=overwritten", "B=2", "C=3"}, env)
+}
+
+func TestEnvironment_SetRemovesDuplicates(t *testing.T) {
+	env := Environment{"A=1", "B=2", "A=3"}
+
+	env.Set("A", "4")
+
+	assert.Equal(t, Environment{"A=4", "B=2"}, env)
+}
+
+func TestEnvironment_Unset(t *testing.T) {
+	env := Environment{"A=1", "B=2", "A=3"}
+
+	env.Unset("A")
+	env.Unset("MISSING")
+
+	assert.Equal(t, Environment{"B=2"}, env)
+}
+
+func TestEnvironment_Get(t *testing.T) {
+	env := Environment{"A=1", "EMPTY=", "A=last=wins"}
+
+	value, ok := env.Get("A")
+	assert.True(t, ok)
+	assert.Equal(t, "last=wins", value)
+
+	value, ok = env.Get("EMPTY")
+	assert.True(t, ok)
+	assert.Equal(t, "", value)
+
+	_, ok = env.Get("MISSING")
+	assert.False(t, ok)
+}
+
+func TestEnvironment_Deduplicate(t *testing.T) {
+	env := Environment{"A=1", "B=2", "A=3", "C=4", "B=5"}
+
+	env.deduplicate()
+
+	assert.Equal(t, Environment{"A=3", "B=5", "C=4"}, env)
+}
+
+func TestEnvironment_DeduplicateLeadingEquals(t *testing.T) {
+	env := Environment{`=C:=C:\a`, `=D:=D:\b`, "A=1", `=C:=C:\c`}
+
+	env.deduplicate()
+
+	assert.Equal(t, Environment{`=C:=C:\c`, `=D:=D:\b`, "A=1"}, env)
+
+	value, ok := env.Get("=D:")
+	assert.True(t, ok)
+	assert.Equal(t, `D:\b`, value)
+}
+
+func TestCommand_AddEnvOverrides(t *testing.T) {
+	c := NewCommand("echo test")
+
+	c.AddEnv("KEY", "first")
+	c.AddEnv("OTHER", "value")
+	c.AddEnv("KEY", "second")
+
+	assert.Equal(t, []string{"KEY=second", "OTHER=value"}, c.Env)
+}
+
+func TestWithEnvironmentVariables_Ordering(t *testing.T) {
+	c := NewCommand("echo test", WithEnvironmentVariables(EnvVars{"C": "3", "A": "1", "B": "2"}))
+
+	assert.Equal(t, []string{"A=1", "B=2", "C=3"}, c.Env)
+}
+
+func TestWithInheritedEnvironment_WithoutDuplicates(t *testing.T) {
+	os.Setenv("CMD_TEST_INHERITED", "from os")
+	defer os.Unsetenv("CMD_TEST_INHERITED")
+
+	c := NewCommand("echo test", WithInheritedEnvironment(EnvVars{"CMD_TEST_INHERITED": "overwritten"}))
+
+	count := 0
+	for _, e := range c.Env {
+		if e == "CMD_TEST_INHERITED=overwritten" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+	assert.NotContains(t, c.Env, "CMD_TEST_INHERITED=from os")
+}
+
+func TestWithoutEnv(t *testing.T) {
+	os.Setenv("CMD_TEST_SECRET", "secret")
+	defer os.Unsetenv("CMD_TEST_SECRET")
+
+	c := NewCommand(
+		"echo test",
+		WithInheritedEnvironment(EnvVars{"CMD_TEST_OTHER": "value"}),
+		WithoutEnv("CMD_TEST_SECRET", "CMD_TEST_OTHER"),
+	)
+
+	_, ok := c.Environment().Get("CMD_TEST_SECRET")
+	assert.False(t, ok)
+	_, ok = c.Environment().Get("CMD_TEST_OTHER")
+	assert.False(t, ok)
+	_, ok = c.Environment().Get("PATH")
+	assert.True(t, ok)
+}
+
+func TestCommand_AddEnvLiteral(t *testing.T) {
+	c := NewCommand("echo test")
+
+	c.AddEnvLiteral("PASSWORD", "pa$$word${HOME}")
+
+	assert.Equal(t, []string{"PASSWORD=pa$$word${HOME}"}, c.Env)
+}
+
+func TestWithEnvExpansion_NoExpansion(t *testing.T) {
+	c := NewCommand("echo test", WithEnvExpansion(NoExpansion), WithEnvironmentVariables(EnvVars{"REGEX": "^a$|${b}"}))
+
+	assert.Equal(t, []string{"REGEX=^a$|${b}"}, c.Env)
+}
+
+func TestWithEnvExpansion_ExpandFromCommand(t *testing.T) {
+	os.Setenv("CMD_TEST_HOST", "from process")
+	defer os.Unsetenv("CMD_TEST_HOST")
+
+	c := NewCommand("echo test", WithEnvExpansion(ExpandFromCommand))
+	c.AddEnv("CMD_TEST_HOST", "example.com")
+	c.AddEnv("URL", "https://${CMD_TEST_HOST}/$MISSING")
+
+	value, _ := c.Environment().Get("URL")
+	assert.Equal(t, "https://example.com/", value)
+	assert.Nil(t, c.optionErr)
+}
+
+func TestWithStrictEnvExpansion(t *testing.T) {
+	c := NewCommand(
+		"echo test",
+		WithStrictEnvExpansion,
+		WithEnvExpansion(ExpandFromCommand),
+		WithEnvironmentVariables(EnvVars{"A": "value", "B": "${A} ${CMD_TEST_UNDEFINED}"}),
+	)
+
+	err := c.Execute()
+
+	var undefinedErr *UndefinedEnvError
+	assert.ErrorAs(t, err, &undefinedErr)
+	assert.Equal(t, "B", undefinedErr.Key)
+	assert.EqualError(t, err, "env variable B references undefined variable CMD_TEST_UNDEFINED")
+	assert.False(t, c.Executed())
+}
+
+func TestWithStrictEnvExpansion_FromProcess(t *testing.T) {
+	os.Setenv("CMD_TEST_DEFINED", "")
+	defer os.Unsetenv("CMD_TEST_DEFINED")
+
+	c := NewCommand("echo test", WithStrictEnvExpansion)
+	c.AddEnv("KEY", "${CMD_TEST_DEFINED}")
+
+	assert.Nil(t, c.optionErr)
+}
+
+func TestWithInheritedEnvironmentFiltered(t *testing.T) {
+	os.Setenv("CMD_TEST_AWS_SECRET_ACCESS_KEY", "secret")
+	os.Setenv("CMD_TEST_GITHUB_TOKEN", "token")
+	os.Setenv("CMD_TEST_KEEP", "keep")
+	defer func() {
+		os.Unsetenv("CMD_TEST_AWS_SECRET_ACCESS_KEY")
+		os.Unsetenv("CMD_TEST_GITHUB_TOKEN")
+		os.Unsetenv("CMD_TEST_KEEP")
+	}()
+
+	c := NewCommand("echo test", WithInheritedEnvironmentFiltered(
+		EnvFilter{Allow: []string{"CMD_TEST_*"}, Deny: []string{"CMD_TEST_AWS_*", "*_TOKEN"}},
+		EnvVars{"CMD_TEST_TOKEN": "added"},
+	))
+
+	assert.Nil(t, c.optionErr)
+	assert.Equal(t, []string{"CMD_TEST_KEEP=keep", "CMD_TEST_TOKEN=added"}, c.Env)
+}
+
+func TestWithInheritedEnvironmentFiltered_DenyOnly(t *testing.T) {
+	os.Setenv("CMD_TEST_SECRET", "secret")
+	defer os.Unsetenv("CMD_TEST_SECRET")
+
+	c := NewCommand("echo test", WithInheritedEnvironmentFiltered(EnvFilter{Deny: []string{"CMD_TEST_SECRET"}}, nil))
+
+	assert.Equal(t, len(os.Environ())-1, len(c.Env))
+	assert.NotContains(t, c.Env, "CMD_TEST_SECRET=secret")
+}
+
+func TestWithInheritedEnvironmentFiltered_InvalidPattern(t *testing.T) {
+	c := NewCommand("echo test", WithInheritedEnvironmentFiltered(EnvFilter{Deny: []string{"[invalid"}}, nil))
+
+	err := c.Execute()
+
+	assert.EqualError(t, err, `invalid env filter pattern "[invalid": syntax error in pattern`)
+}
+
+func TestWithMinimalEnvironment(t *testing.T) {
+	os.Setenv("CMD_TEST_SECRET", "secret")
+	defer os.Unsetenv("CMD_TEST_SECRET")
+
+	c := NewCommand("echo test", WithMinimalEnvironment(EnvVars{"CI": "true"}))
+
+	for _, e := range c.Env {
+		key, _, _ := strings.Cut(e, "=")
+		assert.Contains(t, append(minimalEnvironment, "CI"), key)
+	}
+	_, ok := c.Environment().Get("CI")
+	assert.True(t, ok)
+}
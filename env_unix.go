@@ -0,0 +1,6 @@
+//go:build !windows
+
+package cmd
+
+// minimalEnvironment are the variables inherited by WithMinimalEnvironment
+var minimalEnvironment = []string{"PATH", "HOME", "LANG", "TERM", "USER"}
@@ -0,0 +1,5 @@
+package cmd
+
+// minimalEnvironment are the variables inherited by WithMinimalEnvironment,
+// cmd.exe requires SystemRoot and ComSpec to run most commands
+var minimalEnvironment = []string{"PATH", "Path", "SystemRoot", "ComSpec", "PATHEXT", "TEMP", "TMP", "USERNAME", "USERPROFILE"}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvFileError describes an invalid line in an env file
+type EnvFileError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *EnvFileError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// WithEnvFile reads environment variables from dotenv files and adds them to the command
+// Files are read in order, later files override earlier ones. Errors are
+// returned by Execute and ExecuteContext before the command is started.
+//
+// Supported syntax:
+//
+//	# comments and empty lines are ignored
+//	export KEY=value            # export prefixes and inline comments
+//	SINGLE='literal ${VALUE}'   # single quoted values are not expanded
+//	DOUBLE="line one\nline two" # double quoted values support escapes
+//	MULTILINE="first line
+//	second line"
+//	URL=http://${HOST}:$PORT    # variables from the files, then like AddEnv
+//
+// Unquoted and double quoted values are expanded like AddEnv, variables of the files take
+// precedence, see WithEnvExpansion and WithStrictEnvExpansion.
+//
+// Example:
+//
+//	c := cmd.NewCommand("./deploy.sh", cmd.WithEnvFile(".env", ".env.production"))
+func WithEnvFile(paths ...string) func(c *Command) {
+	return func(c *Command) {
+		defined := map[string]string{}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				c.addOptionError(err)
+				return
+			}
+
+			vars, err := c.parseEnvFile(path, string(data), defined)
+			if err != nil {
+				c.addOptionError(err)
+				return
+			}
+
+			for _, v := range vars {
+				c.Environment().Set(v[0], v[1])
+			}
+		}
+	}
+}
+
+// parseEnvFile returns the variables of a dotenv file as key value pairs in the order they are defined
+// Values are expanded by the command, the variables in defined take precedence and
+// the variables of the file are added to it.
+func (c *Command) parseEnvFile(name, data string, defined map[string]string) ([][2]string, error) {
+	var vars [][2]string
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(strings.TrimSuffix(lines[i], "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "export"); ok && (strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, "\t")) {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, &EnvFileError{File: name, Line: lineNo, Msg: fmt.Sprintf("expected KEY=VALUE, got %q", line)}
+		}
+		if !isEnvKey(key) {
+			return nil, &EnvFileError{File: name, Line: lineNo, Msg: fmt.Sprintf("invalid variable name %q", key)}
+		}
+		value = strings.TrimSpace(value)
+
+		quote := byte(0)
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote = value[0]
+		}
+
+		switch quote {
+		case 0:
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = strings.TrimSpace(value[:idx])
+			}
+		default:
+			// Quoted values may continue on the following lines
+			quoted := value[1:]
+			end := closingQuote(quoted, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				quoted += "\n" + strings.TrimSuffix(lines[i], "\r")
+				end = closingQuote(quoted, quote)
+			}
+			if end < 0 {
+				return nil, &EnvFileError{File: name, Line: lineNo, Msg: fmt.Sprintf("unterminated quoted value for %s", key)}
+			}
+
+			rest := strings.TrimSpace(quoted[end+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, &EnvFileError{File: name, Line: i + 1, Msg: fmt.Sprintf("unexpected characters %q after quoted value", rest)}
+			}
+
+			value = quoted[:end]
+		}
+
+		if quote != '\'' {
+			var err error
+			value, err = expandEnvValue(value, func(s string) (string, error) {
+				return c.expandEnvWith(key, s, defined)
+			}, quote == '"')
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+			}
+		}
+
+		defined[key] = value
+		vars = append(vars, [2]string{key, value})
+	}
+
+	return vars, nil
+}
+
+// closingQuote returns the index of the closing quote, double quotes can be escaped
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// expandEnvValue expands the variables of s and, if escapes is set, resolves
+// backslash escapes, escaped characters like \$ are not expanded
+func expandEnvValue(s string, expand func(string) (string, error), escapes bool) (string, error) {
+	if !escapes {
+		return expand(s)
+	}
+
+	var b strings.Builder
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			continue
+		}
+
+		expanded, err := expand(s[start:i])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(expanded)
+
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+		start = i + 1
+	}
+
+	expanded, err := expand(s[start:])
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(expanded)
+	return b.String(), nil
+}
+
+func isEnvKey(key string) bool {
+	if key == "" || isDigit(key[0]) {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] != '_' && !isAlpha(key[i]) && !isDigit(key[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEnvFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestWithEnvFile(t *testing.T) {
+	os.Setenv("CMD_TEST_ENV_FILE_HOST", "example.com")
+	defer os.Unsetenv("CMD_TEST_ENV_FILE_HOST")
+
+	path := writeEnvFile(t, `# comment
+PLAIN=value
+export EXPORTED=exported
+  SPACED = spaced value  # inline comment
+EMPTY=
+SINGLE='literal ${PLAIN} # no comment'
+DOUBLE="tab\tquote\" dollar\$PLAIN"
+MULTILINE="first line
+second line"
+PORT=8080
+URL=http://${CMD_TEST_ENV_FILE_HOST}:$PORT/$PLAIN
+WINDOWS=crlf`+"\r\n")
+
+	c := NewCommand("echo", WithEnvFile(path))
+
+	assert.Nil(t, c.optionErr)
+	assert.Equal(t, []string{
+		"PLAIN=value",
+		"EXPORTED=exported",
+		"SPACED=spaced value",
+		"EMPTY=",
+		"SINGLE=literal ${PLAIN} # no comment",
+		"DOUBLE=tab\tquote\" dollar$PLAIN",
+		"MULTILINE=first line\nsecond line",
+		"PORT=8080",
+		"URL=http://example.com:8080/value",
+		"WINDOWS=crlf",
+	}, c.Env)
+}
+
+func TestWithEnvFile_MultipleFiles(t *testing.T) {
+	base := writeEnvFile(t, "NAME=base\nGREETING=hello")
+	override := writeEnvFile(t, "NAME=override\nMESSAGE=\"${GREETING} ${NAME}\"")
+
+	c := NewCommand("echo", WithEnvFile(base, override))
+
+	assert.Nil(t, c.optionErr)
+	assert.Equal(t, []string{"NAME=override", "GREETING=hello", "MESSAGE=hello override"}, c.Env)
+}
+
+func TestWithEnvFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		msg     string
+	}{
+		{"missing equals", "VALID=1\nINVALID", 2, `expected KEY=VALUE, got "INVALID"`},
+		{"invalid key", "1KEY=value", 1, `invalid variable name "1KEY"`},
+		{"key with space", "MY KEY=value", 1, `invalid variable name "MY KEY"`},
+		{"unterminated quote", "A=1\nB=\"never\nclosed", 2, "unterminated quoted value for B"},
+		{"characters after quote", "A=\"quoted\" trailing", 1, `unexpected characters "trailing" after quoted value`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeEnvFile(t, tt.content)
+
+			c := NewCommand("echo", WithEnvFile(path))
+			err := c.Execute()
+
+			var envErr *EnvFileError
+			require.True(t, errors.As(err, &envErr))
+			assert.Equal(t, path, envErr.File)
+			assert.Equal(t, tt.line, envErr.Line)
+			assert.Equal(t, tt.msg, envErr.Msg)
+			assert.False(t, c.Executed())
+		})
+	}
+}
+
+func TestWithEnvFile_MissingFile(t *testing.T) {
+	c := NewCommand("echo", WithEnvFile(filepath.Join(t.TempDir(), "missing.env")))
+
+	err := c.Execute()
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWithEnvFile_ExpandFromCommand(t *testing.T) {
+	path := writeEnvFile(t, "URL=http://${HOST}/$UNDEFINED\nQUOTED=\"${HOST}:\\$PORT\"")
+
+	c := NewCommand("echo",
+		WithEnvExpansion(ExpandFromCommand),
+		WithEnvironmentVariables(EnvVars{"HOST": "example.com"}),
+		WithEnvFile(path),
+	)
+
+	assert.Nil(t, c.optionErr)
+	assert.Equal(t, []string{"HOST=example.com", "URL=http://example.com/", "QUOTED=example.com:$PORT"}, c.Env)
+}
+
+func TestWithEnvFile_StrictEnvExpansion(t *testing.T) {
+	path := writeEnvFile(t, "A=1\nURL=http://${HOST}/$UNDEFINED")
+
+	c := NewCommand("echo",
+		WithEnvExpansion(ExpandFromCommand),
+		WithStrictEnvExpansion,
+		WithEnvironmentVariables(EnvVars{"HOST": "example.com"}),
+		WithEnvFile(path),
+	)
+	err := c.Execute()
+
+	var undefinedErr *UndefinedEnvError
+	require.ErrorAs(t, err, &undefinedErr)
+	assert.Equal(t, "URL", undefinedErr.Key)
+	assert.EqualError(t, err, path+":2: env variable URL references undefined variable UNDEFINED")
+	assert.False(t, c.Executed())
+}
+
+func TestWithEnvFile_NoExpansion(t *testing.T) {
+	path := writeEnvFile(t, "PLAIN=$HOME\nDOUBLE=\"${HOME}\\tliteral\"")
+
+	c := NewCommand("echo", WithEnvExpansion(NoExpansion), WithEnvFile(path))
+
+	assert.Nil(t, c.optionErr)
+	assert.Equal(t, []string{"PLAIN=$HOME", "DOUBLE=${HOME}\tliteral"}, c.Env)
+}
@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Graph executes commands according to their dependencies
+// Nodes without dependencies between each other run in parallel.
+type Graph struct {
+	Nodes []*Node
+	// Concurrency limits the number of commands running at the same time,
+	// zero means no limit
+	Concurrency int
+}
+
+// Node is a named command which runs after all of its dependencies succeeded
+type Node struct {
+	Name      string
+	Command   *Command
+	DependsOn []string
+}
+
+// NodeResult holds the result of a node
+// Nodes are skipped if one of their dependencies failed or was skipped.
+type NodeResult struct {
+	Name  string
+	Start time.Time
+	StepResult
+}
+
+// GraphResult holds the results of all nodes in topological order
+type GraphResult struct {
+	Nodes []NodeResult
+}
+
+// CycleError is returned if the dependencies of the nodes contain a cycle
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// NewGraph creates a new graph
+// You can add option with variadic option argument
+//
+// Example:
+//
+//	g := cmd.NewGraph()
+//	g.Add("generate", cmd.NewCommand("go generate ./..."))
+//	g.Add("compile", cmd.NewCommand("go build ./..."), "generate")
+//	g.Add("test", cmd.NewCommand("go test ./..."), "compile")
+//	g.Add("lint", cmd.NewCommand("go vet ./..."))
+//	result, err := g.Run(context.Background())
+func NewGraph(options ...func(*Graph)) *Graph {
+	g := &Graph{}
+
+	for _, o := range options {
+		o(g)
+	}
+
+	return g
+}
+
+// WithGraphConcurrency limits the number of commands of a graph running at the same time
+func WithGraphConcurrency(n int) func(g *Graph) {
+	return func(g *Graph) {
+		g.Concurrency = n
+	}
+}
+
+// Add adds a command which depends on the nodes with the given names
+// Dependencies may be added after the node which depends on them.
+func (g *Graph) Add(name string, c *Command, dependsOn ...string) *Node {
+	n := &Node{Name: name, Command: c, DependsOn: dependsOn}
+	g.Nodes = append(g.Nodes, n)
+	return n
+}
+
+// Validate checks that all node names are unique, all dependencies exist
+// and that there are no dependency cycles
+func (g *Graph) Validate() error {
+	_, err := g.topologicalOrder()
+	return err
+}
+
+// topologicalOrder returns the node indices in an order where every node comes
+// after its dependencies. Independent nodes keep the order they were added in.
+func (g *Graph) topologicalOrder() ([]int, error) {
+	index := make(map[string]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		if _, ok := index[n.Name]; ok {
+			return nil, fmt.Errorf("duplicate node %q", n.Name)
+		}
+		index[n.Name] = i
+	}
+
+	for _, n := range g.Nodes {
+		for _, dep := range n.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("node %q depends on unknown node %q", n.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.Nodes))
+	order := make([]int, 0, len(g.Nodes))
+	var path []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != g.Nodes[i].Name {
+				start++
+			}
+			cycle := append(append([]string{}, path[start:]...), g.Nodes[i].Name)
+			return &CycleError{Path: cycle}
+		}
+
+		state[i] = visiting
+		path = append(path, g.Nodes[i].Name)
+		for _, dep := range g.Nodes[i].DependsOn {
+			if err := visit(index[dep]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		order = append(order, i)
+		return nil
+	}
+
+	for i := range g.Nodes {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// Run validates the graph and executes all nodes whose dependencies succeeded
+// A node fails if it returns an error or exits with an unexpected exit code,
+// the returned error contains an error for every failed node.
+func (g *Graph) Run(ctx context.Context) (*GraphResult, error) {
+	order, err := g.topologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	index := make(map[string]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		index[n.Name] = i
+	}
+
+	pending := make([]int, len(g.Nodes))
+	dependents := make([][]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		pending[i] = len(n.DependsOn)
+		for _, dep := range n.DependsOn {
+			dependents[index[dep]] = append(dependents[index[dep]], i)
+		}
+	}
+
+	results := make([]NodeResult, len(g.Nodes))
+	blocked := make([]bool, len(g.Nodes))
+	var ready []int
+	for _, i := range order {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	// complete releases the dependents of a finished or skipped node
+	var complete func(i int, ok bool)
+	complete = func(i int, ok bool) {
+		for _, d := range dependents[i] {
+			blocked[d] = blocked[d] || !ok
+			pending[d]--
+			if pending[d] > 0 {
+				continue
+			}
+			if blocked[d] {
+				results[d] = NodeResult{Name: g.Nodes[d].Name, StepResult: StepResult{Command: g.Nodes[d].Command, Skipped: true}}
+				complete(d, false)
+				continue
+			}
+			ready = append(ready, d)
+		}
+	}
+
+	done := make(chan int)
+	running := 0
+	for {
+		for len(ready) > 0 && (g.Concurrency <= 0 || running < g.Concurrency) {
+			i := ready[0]
+			ready = ready[1:]
+			n := g.Nodes[i]
+
+			if ctx.Err() != nil {
+				results[i] = NodeResult{Name: n.Name, StepResult: StepResult{Command: n.Command, Skipped: true}}
+				complete(i, false)
+				continue
+			}
+
+			running++
+			go func(i int, n *Node) {
+				start := time.Now()
+				step := executeStep(ctx, n.Command)
+				results[i] = NodeResult{Name: n.Name, Start: start, StepResult: step}
+				done <- i
+			}(i, n)
+		}
+
+		// Nothing is running and nothing is ready, all nodes were either
+		// executed or skipped
+		if running == 0 {
+			break
+		}
+
+		i := <-done
+		running--
+		complete(i, !results[i].Failed())
+	}
+
+	result := &GraphResult{Nodes: make([]NodeResult, 0, len(order))}
+	var errs []error
+	for _, i := range order {
+		r := results[i]
+		result.Nodes = append(result.Nodes, r)
+		switch {
+		case r.Err != nil:
+			errs = append(errs, fmt.Errorf("node %q failed: %w", r.Name, r.Err))
+		case r.Failed():
+			errs = append(errs, fmt.Errorf("node %q failed with exit code %d", r.Name, r.ExitCode))
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// Failed returns if at least one node failed
+func (r *GraphResult) Failed() bool {
+	for _, n := range r.Nodes {
+		if n.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Node returns the result of the node with the given name
+func (r *GraphResult) Node(name string) (NodeResult, bool) {
+	for _, n := range r.Nodes {
+		if n.Name == name {
+			return n, true
+		}
+	}
+	return NodeResult{}, false
+}
@@ -0,0 +1,133 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_Run(t *testing.T) {
+	g := NewGraph()
+	g.Add("test", NewCommand("echo test"), "compile")
+	g.Add("compile", NewCommand("echo compile"), "generate")
+	g.Add("generate", NewCommand("echo generate"))
+	g.Add("lint", NewCommand("echo lint"))
+
+	result, err := g.Run(context.Background())
+
+	assert.Nil(t, err)
+	assert.False(t, result.Failed())
+
+	var names []string
+	for _, n := range result.Nodes {
+		names = append(names, n.Name)
+		assert.Equal(t, n.Name+"\n", n.Stdout)
+		assert.False(t, n.Start.IsZero())
+	}
+	assert.Equal(t, []string{"generate", "compile", "test", "lint"}, names)
+}
+
+func TestGraph_RunsDependenciesFirst(t *testing.T) {
+	dir := t.TempDir()
+	g := NewGraph()
+	g.Add("first", NewCommand("sleep 0.05; touch "+dir+"/first"))
+	g.Add("second", NewCommand("test -f "+dir+"/first"), "first")
+
+	result, err := g.Run(context.Background())
+
+	assert.Nil(t, err)
+	first, _ := result.Node("first")
+	second, _ := result.Node("second")
+	assert.False(t, second.Start.Before(first.Start.Add(first.Duration)))
+}
+
+func TestGraph_RunsIndependentNodesInParallel(t *testing.T) {
+	g := NewGraph()
+	g.Add("a", NewCommand("sleep 0.2"))
+	g.Add("b", NewCommand("sleep 0.2"))
+	g.Add("c", NewCommand("sleep 0.2"))
+
+	start := time.Now()
+	_, err := g.Run(context.Background())
+
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestGraph_SkipsDependentsOfFailedNodes(t *testing.T) {
+	g := NewGraph()
+	g.Add("generate", NewCommand("exit 1"))
+	g.Add("compile", NewCommand("echo compile"), "generate")
+	g.Add("test", NewCommand("echo test"), "compile")
+	g.Add("lint", NewCommand("echo lint"))
+
+	result, err := g.Run(context.Background())
+
+	assert.EqualError(t, err, `node "generate" failed with exit code 1`)
+	assert.True(t, result.Failed())
+
+	compile, _ := result.Node("compile")
+	test, _ := result.Node("test")
+	lint, _ := result.Node("lint")
+	assert.True(t, compile.Skipped)
+	assert.True(t, test.Skipped)
+	assert.False(t, test.Command.Executed())
+	assert.Equal(t, "lint\n", lint.Stdout)
+}
+
+func TestGraph_WithGraphConcurrency(t *testing.T) {
+	g := NewGraph(WithGraphConcurrency(1))
+	g.Add("a", NewCommand("sleep 0.1"))
+	g.Add("b", NewCommand("sleep 0.1"))
+
+	start := time.Now()
+	_, err := g.Run(context.Background())
+
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestGraph_DetectsCycles(t *testing.T) {
+	g := NewGraph()
+	g.Add("a", NewCommand("echo a"), "c")
+	g.Add("b", NewCommand("echo b"), "a")
+	g.Add("c", NewCommand("echo c"), "b")
+
+	result, err := g.Run(context.Background())
+
+	var cycleErr *CycleError
+	assert.Nil(t, result)
+	assert.True(t, errors.As(err, &cycleErr))
+	assert.Equal(t, []string{"a", "c", "b", "a"}, cycleErr.Path)
+	assert.EqualError(t, err, "dependency cycle: a -> c -> b -> a")
+	assert.False(t, g.Nodes[0].Command.Executed())
+}
+
+func TestGraph_Validate(t *testing.T) {
+	g := NewGraph()
+	g.Add("a", NewCommand("echo a"), "missing")
+	assert.EqualError(t, g.Validate(), `node "a" depends on unknown node "missing"`)
+
+	g = NewGraph()
+	g.Add("a", NewCommand("echo a"))
+	g.Add("a", NewCommand("echo a"))
+	assert.EqualError(t, g.Validate(), `duplicate node "a"`)
+}
+
+func TestGraph_SkippedNodeWithExpectedExitCodes(t *testing.T) {
+	g := NewGraph()
+	g.Add("generate", NewCommand("exit 2"))
+	g.Add("search", NewCommand("grep x /dev/null", WithExpectedExitCodes(1)), "generate")
+
+	result, err := g.Run(context.Background())
+
+	assert.EqualError(t, err, `node "generate" failed with exit code 2`)
+	search, _ := result.Node("search")
+	assert.True(t, search.Skipped)
+	assert.False(t, search.Failed())
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Group executes commands in parallel
+type Group struct {
+	Commands []*Command
+	// Concurrency limits the number of commands running at the same time,
+	// zero means no limit
+	Concurrency int
+	// FailFast cancels all other commands after the first command failed
+	FailFast bool
+}
+
+// NewGroup creates a new group
+// You can add option with variadic option argument
+//
+// Example:
+//
+//	g := cmd.NewGroup(cmd.WithConcurrency(4), cmd.WithFailFast)
+//	g.Add(cmd.NewCommand("make lint"))
+//	g.Add(cmd.NewCommand("make test"))
+//	results, err := g.Run(context.Background())
+func NewGroup(options ...func(*Group)) *Group {
+	g := &Group{}
+
+	for _, o := range options {
+		o(g)
+	}
+
+	return g
+}
+
+// WithConcurrency limits the number of commands running at the same time
+func WithConcurrency(n int) func(g *Group) {
+	return func(g *Group) {
+		g.Concurrency = n
+	}
+}
+
+// WithFailFast cancels all other commands of the group after the first command failed
+func WithFailFast(g *Group) {
+	g.FailFast = true
+}
+
+// Add adds a command to the group
+func (g *Group) Add(c *Command) {
+	g.Commands = append(g.Commands, c)
+}
+
+// Run executes all commands of the group and waits until they are finished
+// The results are returned in the order the commands were added.
+// A command fails if it returns an error or exits with an unexpected exit code,
+// the returned error joins a *StepError for every failed command.
+func (g *Group) Run(ctx context.Context) ([]StepResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := g.Concurrency
+	if limit <= 0 {
+		limit = len(g.Commands)
+	}
+	sem := make(chan struct{}, limit)
+
+	// Commands are started in the order they were added
+	results := make([]StepResult, len(g.Commands))
+	var wg sync.WaitGroup
+	for i, c := range g.Commands {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		// Another command may have failed while this one was waiting
+		if ctx.Err() != nil {
+			results[i] = StepResult{Command: c, Skipped: true}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, c *Command) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = executeStep(ctx, c)
+			if g.FailFast && results[i].Failed() {
+				cancel()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, r := range results {
+		errs = append(errs, r.asError(i))
+	}
+
+	return results, errors.Join(errs...)
+}
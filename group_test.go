@@ -0,0 +1,86 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup_Run(t *testing.T) {
+	g := NewGroup()
+	g.Add(NewCommand("sleep 0.05; echo first"))
+	g.Add(NewCommand("echo second"))
+	g.Add(NewCommand("exit 3"))
+
+	results, err := g.Run(context.Background())
+
+	assert.EqualError(t, err, "step 2 (exit 3) failed with exit code 3")
+	assert.Len(t, results, 3)
+	assert.Equal(t, "first\n", results[0].Stdout)
+	assert.Equal(t, "second\n", results[1].Stdout)
+	assert.Equal(t, 3, results[2].ExitCode)
+}
+
+func TestGroup_RunsInParallel(t *testing.T) {
+	g := NewGroup()
+	for i := 0; i < 4; i++ {
+		g.Add(NewCommand("sleep 0.2"))
+	}
+
+	start := time.Now()
+	_, err := g.Run(context.Background())
+
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), 700*time.Millisecond)
+}
+
+func TestGroup_WithConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	g := NewGroup(WithConcurrency(2))
+	for i := 0; i < 6; i++ {
+		// Every command counts the running commands by creating a file
+		// in dir and writes the count it observed to stdout
+		g.Add(NewCommand(fmt.Sprintf("touch %[1]s/%[2]d; ls %[1]s | wc -l; sleep 0.1; rm %[1]s/%[2]d", dir, i)))
+	}
+
+	results, err := g.Run(context.Background())
+
+	assert.Nil(t, err)
+	for _, r := range results {
+		assert.Contains(t, []string{"1", "2"}, strings.TrimSpace(r.Stdout))
+	}
+}
+
+func TestGroup_WithFailFast(t *testing.T) {
+	g := NewGroup(WithConcurrency(2), WithFailFast)
+	g.Add(NewCommand("exit 1"))
+	g.Add(NewCommand("sleep 2"))
+	g.Add(NewCommand("echo never"))
+
+	start := time.Now()
+	results, err := g.Run(context.Background())
+
+	assert.NotNil(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, results[0].ExitCode)
+	assert.ErrorIs(t, results[1].Err, context.Canceled)
+	assert.True(t, results[2].Skipped)
+}
+
+func TestGroup_WithFailFastSkipsExpectedExitCodes(t *testing.T) {
+	g := NewGroup(WithConcurrency(1), WithFailFast)
+	g.Add(NewCommand("exit 2"))
+	g.Add(NewCommand("grep x /dev/null", WithExpectedExitCodes(1)))
+
+	results, err := g.Run(context.Background())
+
+	assert.EqualError(t, err, "step 0 (exit 2) failed with exit code 2")
+	assert.True(t, results[1].Skipped)
+	assert.False(t, results[1].Failed())
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks are called during the execution of a command, see WithHooks
+type Hooks struct {
+	// BeforeStart is called before the command is started, it can modify the
+	// command, e.g. its env. If it returns an error the command is not started
+	// and the error is returned by ExecuteContext, like errors of options
+	// applied by the hook, e.g. AddEnv with WithStrictEnvExpansion.
+	BeforeStart func(ctx context.Context, c *Command) error
+	// AfterStart is called after the process of the command was started
+	AfterStart func(c *Command, pid int)
+	// AfterExit is called after the process exited or was killed with the
+	// error which is returned by ExecuteContext
+	AfterExit func(c *Command, err error)
+}
+
+// Executor executes a command, see Middleware
+type Executor func(ctx context.Context, c *Command) error
+
+// Middleware wraps the execution of commands
+// A middleware can modify the command before it calls next, read the results
+// afterwards, or return an error without calling next to prevent the execution.
+//
+// Example:
+//
+//	func timing(next cmd.Executor) cmd.Executor {
+//	    return func(ctx context.Context, c *cmd.Command) error {
+//	        start := time.Now()
+//	        err := next(ctx, c)
+//	        metrics.Observe(c.Command, time.Since(start))
+//	        return err
+//	    }
+//	}
+//
+//	c := cmd.NewCommand("make", cmd.WithMiddleware(timing))
+type Middleware func(next Executor) Executor
+
+// WithMiddleware executes the command through the middleware
+// Middleware is called in the order it was added.
+func WithMiddleware(middleware ...Middleware) func(c *Command) {
+	return func(c *Command) {
+		c.middlewares = append(c.middlewares, middleware...)
+	}
+}
+
+// WithHooks calls the hooks during the execution of the command
+// Hooks of multiple WithHooks options are called in order.
+//
+// Example:
+//
+//	c := cmd.NewCommand("deploy.sh", cmd.WithHooks(cmd.Hooks{
+//	    BeforeStart: func(ctx context.Context, c *cmd.Command) error {
+//	        if !authorized(ctx) {
+//	            return errors.New("not authorized to deploy")
+//	        }
+//	        c.AddEnv("DEPLOY_TOKEN", token(ctx))
+//	        return nil
+//	    },
+//	    AfterExit: func(c *cmd.Command, err error) {
+//	        log.Printf("deploy exited with %d", c.ExitCode())
+//	    },
+//	}))
+func WithHooks(hooks Hooks) func(c *Command) {
+	return func(c *Command) {
+		c.hooks = append(c.hooks, hooks)
+	}
+}
+
+// executor returns the executor of the command wrapped by its middleware
+func (c *Command) executor() Executor {
+	executor := Executor(run)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		executor = c.middlewares[i](executor)
+	}
+	return executor
+}
+
+// run executes the command and records its result
+func run(ctx context.Context, c *Command) error {
+	c.startedAt = time.Now()
+	c.err = c.execute(ctx)
+	c.finishedAt = time.Now()
+
+	if c.executed {
+		for _, h := range c.hooks {
+			if h.AfterExit != nil {
+				h.AfterExit(c, c.err)
+			}
+		}
+	}
+	return c.err
+}
+
+// beforeStart calls the BeforeStart hooks and returns errors of options,
+// e.g. of env variables added by the hooks, see WithStrictEnvExpansion
+func (c *Command) beforeStart(ctx context.Context) error {
+	for _, h := range c.hooks {
+		if h.BeforeStart == nil {
+			continue
+		}
+		if err := h.BeforeStart(ctx, c); err != nil {
+			return err
+		}
+	}
+	return c.optionErr
+}
+
+func (c *Command) afterStart(pid int) {
+	for _, h := range c.hooks {
+		if h.AfterStart != nil {
+			h.AfterStart(c, pid)
+		}
+	}
+}
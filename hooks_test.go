@@ -0,0 +1,150 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_WithHooks(t *testing.T) {
+	var events []string
+	c := NewCommand("echo $$ $GREETING; exit 3", WithHooks(Hooks{
+		BeforeStart: func(ctx context.Context, c *Command) error {
+			events = append(events, "before start")
+			c.AddEnv("GREETING", "hello")
+			return nil
+		},
+		AfterStart: func(c *Command, pid int) {
+			events = append(events, "after start "+strconv.Itoa(pid))
+		},
+		AfterExit: func(c *Command, err error) {
+			events = append(events, fmt.Sprintf("after exit %d %v", c.ExitCode(), err))
+		},
+	}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	pid, greeting, _ := strings.Cut(strings.TrimSpace(c.Stdout()), " ")
+	assert.Equal(t, "hello", greeting)
+	assert.Equal(t, []string{"before start", "after start " + pid, "after exit 3 <nil>"}, events)
+}
+
+func TestCommand_WithHooksVeto(t *testing.T) {
+	vetoErr := errors.New("not allowed")
+	var events []string
+
+	c := NewCommand("echo hello",
+		WithHooks(Hooks{
+			BeforeStart: func(ctx context.Context, c *Command) error { return vetoErr },
+			AfterStart:  func(c *Command, pid int) { events = append(events, "after start") },
+			AfterExit:   func(c *Command, err error) { events = append(events, "after exit") },
+		}),
+		WithHooks(Hooks{
+			BeforeStart: func(ctx context.Context, c *Command) error {
+				events = append(events, "second before start")
+				return nil
+			},
+		}),
+	)
+	err := c.Execute()
+
+	assert.ErrorIs(t, err, vetoErr)
+	assert.False(t, c.Executed())
+	assert.Empty(t, events)
+}
+
+func TestCommand_WithHooksStrictEnvExpansion(t *testing.T) {
+	c := NewCommand("echo $A", WithStrictEnvExpansion, WithHooks(Hooks{
+		BeforeStart: func(ctx context.Context, c *Command) error {
+			c.AddEnv("A", "${CMD_TEST_UNDEFINED}")
+			return nil
+		},
+	}))
+	err := c.Execute()
+
+	var undefinedErr *UndefinedEnvError
+	assert.ErrorAs(t, err, &undefinedErr)
+	assert.False(t, c.Executed())
+}
+
+func TestCommand_WithHooksAfterExitOnTimeout(t *testing.T) {
+	var exitErr error
+	c := NewCommand("sleep 1", WithTimeout(10*time.Millisecond), WithHooks(Hooks{
+		AfterExit: func(c *Command, err error) { exitErr = err },
+	}))
+	err := c.Execute()
+
+	assert.EqualError(t, err, "command timed out after 10ms")
+	assert.Equal(t, err, exitErr)
+}
+
+func TestCommand_WithMiddleware(t *testing.T) {
+	var events []string
+	trace := func(name string) Middleware {
+		return func(next Executor) Executor {
+			return func(ctx context.Context, c *Command) error {
+				events = append(events, name+" before")
+				err := next(ctx, c)
+				events = append(events, fmt.Sprintf("%s after %q", name, c.Stdout()))
+				return err
+			}
+		}
+	}
+	c := NewCommand("echo hello", WithMiddleware(trace("first"), trace("second")))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"first before",
+		"second before",
+		`second after "hello\n"`,
+		`first after "hello\n"`,
+	}, events)
+}
+
+func TestCommand_WithMiddlewareVeto(t *testing.T) {
+	deny := func(next Executor) Executor {
+		return func(ctx context.Context, c *Command) error {
+			if strings.HasPrefix(c.Command, "rm ") {
+				return fmt.Errorf("command %q is not allowed", c.Command)
+			}
+			return next(ctx, c)
+		}
+	}
+	c := NewCommand("rm -rf /tmp/cmd-does-not-exist", WithMiddleware(deny))
+	err := c.Execute()
+
+	assert.EqualError(t, err, `command "rm -rf /tmp/cmd-does-not-exist" is not allowed`)
+	assert.False(t, c.Executed())
+
+	c = NewCommand("echo allowed", WithMiddleware(deny))
+	err = c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "allowed\n", c.Stdout())
+}
+
+func TestCommand_MiddlewareIsUsedBySequences(t *testing.T) {
+	var commands []string
+	record := WithMiddleware(func(next Executor) Executor {
+		return func(ctx context.Context, c *Command) error {
+			commands = append(commands, c.Command)
+			return next(ctx, c)
+		}
+	})
+
+	s := NewSequence([]*Command{NewCommand("echo first", record), NewCommand("echo second", record)})
+	_, err := s.Run(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo first", "echo second"}, commands)
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+)
+
+// LogConfig configures WithLogger
+type LogConfig struct {
+	// Env logs the keys of the env of the command on start, the values are redacted
+	Env bool
+	// OutputTail is the number of bytes at the end of stdout and stderr which
+	// are logged if the command failed, no output is logged if it is 0
+	OutputTail int
+}
+
+// WithLogger logs the lifecycle of the command to the logger
+// The start of the command is logged with its pid, its exit with the exit
+// code and duration. Timeouts, cancellations and errors are logged as warnings or errors.
+//
+// Example:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+//	c := cmd.NewCommand("make test", cmd.WithLogger(logger, cmd.WithLogEnv, cmd.WithLogOutputTail(1024)))
+//	c.Execute()
+func WithLogger(logger *slog.Logger, options ...func(*LogConfig)) func(c *Command) {
+	return func(c *Command) {
+		conf := &LogConfig{}
+		for _, o := range options {
+			o(conf)
+		}
+		c.logger = logger
+		c.logConfig = conf
+	}
+}
+
+// WithLogEnv logs the env of the command with redacted values, see WithLogger
+func WithLogEnv(conf *LogConfig) {
+	conf.Env = true
+}
+
+// WithLogOutputTail logs the last n bytes of stdout and stderr if the command failed, see WithLogger
+func WithLogOutputTail(n int) func(conf *LogConfig) {
+	return func(conf *LogConfig) {
+		conf.OutputTail = n
+	}
+}
+
+func (c *Command) logStart(pid int) {
+	if c.logger == nil {
+		return
+	}
+
+	attrs := []any{slog.String("command", c.Command), slog.Int("pid", pid)}
+	if c.Timeout > 0 {
+		attrs = append(attrs, slog.Duration("timeout", c.Timeout))
+	}
+	if c.logConfig.Env {
+		// Only the env is redacted, a Result would read the output while it is written
+		r := Result{Env: c.Env}
+		WithRedactedEnv(&r)
+		attrs = append(attrs, slog.Any("env", r.Env))
+	}
+	c.logger.Info("command started", attrs...)
+}
+
+func (c *Command) logStartError(err error) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Error("command failed to start", slog.String("command", c.Command), slog.Any("error", err))
+}
+
+func (c *Command) logCanceled(pid int, err error) {
+	if c.logger == nil {
+		return
+	}
+
+	msg := "command canceled"
+	attrs := []any{
+		slog.String("command", c.Command),
+		slog.Int("pid", pid),
+		slog.Duration("duration", time.Since(c.startedAt)),
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		msg = "command timed out"
+		if c.Timeout > 0 {
+			attrs = append(attrs, slog.Duration("timeout", c.Timeout))
+		}
+	}
+	c.logger.Warn(msg, append(attrs, slog.Any("error", err))...)
+}
+
+func (c *Command) logKillError(pid int, err error) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Error("can not kill command",
+		slog.String("command", c.Command),
+		slog.Int("pid", pid),
+		slog.Any("error", err),
+	)
+}
+
+func (c *Command) logExit(pid int) {
+	if c.logger == nil {
+		return
+	}
+
+	attrs := []any{
+		slog.String("command", c.Command),
+		slog.Int("pid", pid),
+		slog.Int("exit_code", c.exitCode),
+		slog.Duration("duration", time.Since(c.startedAt)),
+	}
+	if c.signal != 0 {
+		attrs = append(attrs, slog.String("signal", c.signal.String()))
+	}
+
+	if c.isExpectedExitCode(c.exitCode) {
+		c.logger.Info("command exited", attrs...)
+		return
+	}
+
+	if n := c.logConfig.OutputTail; n > 0 {
+		attrs = append(attrs,
+			slog.String("stdout", tail(c.stdout.String(), n)),
+			slog.String("stderr", tail(c.stderr.String(), n)),
+		)
+	}
+	c.logger.Warn("command failed", attrs...)
+}
+
+// tail returns the last n bytes of s without splitting a multi byte character
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords returns the records written by a JSON logger
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func TestCommand_WithLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := NewCommand("echo hello",
+		WithLogger(newTestLogger(buf), WithLogEnv),
+		WithEnvironmentVariables(EnvVars{"TOKEN": "secret"}),
+		WithTimeout(time.Minute),
+	)
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "secret")
+
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+
+	started := records[0]
+	assert.Equal(t, "INFO", started["level"])
+	assert.Equal(t, "command started", started["msg"])
+	assert.Equal(t, "echo hello", started["command"])
+	assert.NotZero(t, started["pid"])
+	assert.Equal(t, float64(time.Minute), started["timeout"])
+	assert.Equal(t, []any{"TOKEN=" + RedactedValue}, started["env"])
+
+	exited := records[1]
+	assert.Equal(t, "INFO", exited["level"])
+	assert.Equal(t, "command exited", exited["msg"])
+	assert.Equal(t, started["pid"], exited["pid"])
+	assert.Equal(t, float64(0), exited["exit_code"])
+	assert.Contains(t, exited, "duration")
+	assert.NotContains(t, exited, "stdout")
+}
+
+func TestCommand_WithLoggerFailure(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := NewCommand("echo first line; echo last line; echo error >&2; exit 2",
+		WithLogger(newTestLogger(buf), WithLogOutputTail(10)),
+	)
+	err := c.Execute()
+
+	require.NoError(t, err)
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+	assert.NotContains(t, records[0], "env")
+
+	failed := records[1]
+	assert.Equal(t, "WARN", failed["level"])
+	assert.Equal(t, "command failed", failed["msg"])
+	assert.Equal(t, float64(2), failed["exit_code"])
+	assert.Equal(t, "last line\n", failed["stdout"])
+	assert.Equal(t, "error\n", failed["stderr"])
+}
+
+func TestCommand_WithLoggerExpectedExitCode(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := NewCommand("exit 1", WithLogger(newTestLogger(buf), WithLogOutputTail(10)), WithExpectedExitCodes(0, 1))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	records := logRecords(t, buf)
+	assert.Equal(t, "command exited", records[1]["msg"])
+}
+
+func TestCommand_WithLoggerTimeout(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := NewCommand("sleep 1", WithLogger(newTestLogger(buf)), WithTimeout(10*time.Millisecond))
+	err := c.Execute()
+
+	require.Error(t, err)
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Equal(t, "command timed out", records[1]["msg"])
+	assert.Equal(t, float64(10*time.Millisecond), records[1]["timeout"])
+}
+
+func TestCommand_WithLoggerCanceled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewCommand("sleep 1", WithLogger(newTestLogger(buf)), WithHooks(Hooks{
+		AfterStart: func(c *Command, pid int) { cancel() },
+	}))
+	err := c.ExecuteContext(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "command canceled", records[1]["msg"])
+	assert.Equal(t, "context canceled", records[1]["error"])
+}
+
+func TestCommand_WithLoggerStartError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := NewCommandArgs([]string{"/cmd-does-not-exist"}, WithLogger(newTestLogger(buf)))
+	err := c.Execute()
+
+	require.Error(t, err)
+	records := logRecords(t, buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "ERROR", records[0]["level"])
+	assert.Equal(t, "command failed to start", records[0]["msg"])
+	assert.Equal(t, err.Error(), records[0]["error"])
+}
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, "short", tail("short", 10))
+	assert.Equal(t, "world", tail("hello world", 5))
+	// The first byte of ö is cut off, so the character is dropped
+	assert.Equal(t, "rld", tail("wörld", 4))
+	assert.Equal(t, "örld", tail("wörld", 5))
+}
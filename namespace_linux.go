@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// Namespace is a linux namespace in which a command can be isolated
+type Namespace uintptr
+
+const (
+	// UserNamespace isolates user and group ids
+	UserNamespace Namespace = syscall.CLONE_NEWUSER
+	// PIDNamespace isolates process ids, the command runs as pid 1
+	PIDNamespace Namespace = syscall.CLONE_NEWPID
+	// MountNamespace isolates mount points
+	MountNamespace Namespace = syscall.CLONE_NEWNS
+	// NetworkNamespace isolates network devices, the command only has
+	// a loopback device which is down and can not reach the host
+	NetworkNamespace Namespace = syscall.CLONE_NEWNET
+	// UTSNamespace isolates the hostname and domain name
+	UTSNamespace Namespace = syscall.CLONE_NEWUTS
+	// IPCNamespace isolates System V IPC and POSIX message queues
+	IPCNamespace Namespace = syscall.CLONE_NEWIPC
+)
+
+// WithNamespaces runs the command in new linux namespaces
+// Without root privileges a user namespace is added automatically, in which
+// the current user is mapped to root. This allows to create the other
+// namespaces without privileges if the kernel allows unprivileged user namespaces.
+//
+// A new PID namespace does not remount /proc, and a new mount namespace
+// may propagate mounts back to the host if the command is started by root
+// and / is a shared mount.
+//
+// Example:
+//
+//	c := cmd.NewCommand("./untrusted-build.sh", cmd.WithNamespaces(
+//	    cmd.NetworkNamespace,
+//	    cmd.PIDNamespace,
+//	    cmd.IPCNamespace,
+//	))
+func WithNamespaces(namespaces ...Namespace) func(c *Command) {
+	var flags uintptr
+	for _, ns := range namespaces {
+		flags |= uintptr(ns)
+	}
+	if flags != 0 && os.Geteuid() != 0 {
+		flags |= uintptr(UserNamespace)
+	}
+
+	return WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+		attr.Cloneflags |= flags
+		if flags&uintptr(UserNamespace) == 0 || len(attr.UidMappings) > 0 {
+			return
+		}
+
+		// Map the current user to root inside of the namespace
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	})
+}
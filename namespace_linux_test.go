@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func skipWithoutNamespaces(t *testing.T) {
+	c := NewCommand("true", WithNamespaces(UserNamespace))
+	if err := c.Execute(); err != nil {
+		t.Skipf("namespaces are not supported: %v", err)
+	}
+}
+
+func TestCommand_WithNamespaces(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	namespaces := map[string]Namespace{
+		"user": UserNamespace,
+		"pid":  PIDNamespace,
+		"mnt":  MountNamespace,
+		"net":  NetworkNamespace,
+		"uts":  UTSNamespace,
+		"ipc":  IPCNamespace,
+	}
+
+	for name, ns := range namespaces {
+		t.Run(name, func(t *testing.T) {
+			host, err := os.Readlink("/proc/self/ns/" + name)
+			require.NoError(t, err)
+
+			c := NewCommand(fmt.Sprintf("readlink /proc/self/ns/%s", name), WithNamespaces(ns))
+			err = c.Execute()
+
+			require.NoError(t, err)
+			assert.Equal(t, 0, c.ExitCode(), c.Stderr())
+			assert.NotEqual(t, host+"\n", c.Stdout())
+		})
+	}
+}
+
+func TestCommand_WithPIDNamespace(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	c := NewCommand("echo $$", WithNamespaces(PIDNamespace))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", c.Stdout())
+}
+
+func TestCommand_WithUserNamespace(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	c := NewCommand("id -u; cat /proc/self/uid_map", WithNamespaces(UserNamespace))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(c.Stdout()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "0", lines[0])
+	assert.Equal(t, []string{"0", fmt.Sprint(os.Geteuid()), "1"}, strings.Fields(lines[1]))
+}
+
+func TestCommand_WithNetworkNamespaceCanNotReachHostLoopback(t *testing.T) {
+	skipWithoutNamespaces(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	// Listening sockets are listed in /proc/net/tcp with their port in hex
+	port := fmt.Sprintf(":%04X ", l.Addr().(*net.TCPAddr).Port)
+
+	c := NewCommand("cat /proc/net/tcp")
+	require.NoError(t, c.Execute())
+	assert.Contains(t, c.Stdout(), port)
+
+	c = NewCommand("cat /proc/net/tcp", WithNamespaces(NetworkNamespace))
+	require.NoError(t, c.Execute())
+	assert.NotContains(t, c.Stdout(), port)
+
+	// /proc/net/dev has two header lines followed by one line per device
+	c = NewCommand("tail -n +3 /proc/net/dev", WithNamespaces(NetworkNamespace))
+	require.NoError(t, c.Execute())
+	assert.Equal(t, "lo:", strings.Fields(c.Stdout())[0])
+	assert.Equal(t, 1, strings.Count(c.Stdout(), "\n"))
+}
+
+func TestCommand_WithNamespacesCombinesWithOtherOptions(t *testing.T) {
+	c := NewCommand("echo hello", WithProcessGroup, WithNamespaces(NetworkNamespace, UTSNamespace), WithDryRun(&DryRun{}))
+	require.NoError(t, c.Execute())
+
+	attr := c.baseCommand.SysProcAttr
+	assert.True(t, attr.Setpgid)
+	assert.NotZero(t, attr.Cloneflags&uintptr(NetworkNamespace))
+	assert.NotZero(t, attr.Cloneflags&uintptr(UTSNamespace))
+}
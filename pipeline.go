@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Pipeline connects the stdout of each command to the stdin of the next
+// command, like `a | b | c` in a shell. All commands run concurrently and
+// keep their own results, so the exit code and stderr of every stage can be
+// read after the pipeline was executed.
+type Pipeline struct {
+	Commands []*Command
+	Timeout  time.Duration
+	// Pipefail sets the exit code of the pipeline to the exit code of the
+	// last command which exited with an unexpected exit code, see WithExpectedExitCodes
+	Pipefail bool
+	executed bool
+}
+
+// NewPipeline creates a new pipeline of the given commands
+// You can add option with variadic option argument
+//
+// Example:
+//
+//	p := cmd.NewPipeline([]*cmd.Command{
+//	    cmd.NewCommand("cat access.log"),
+//	    cmd.NewCommand("grep 404"),
+//	    cmd.NewCommand("wc -l"),
+//	}, cmd.WithPipefail)
+//	p.Execute()
+func NewPipeline(commands []*Command, options ...func(*Pipeline)) *Pipeline {
+	p := &Pipeline{
+		Commands: commands,
+	}
+
+	for _, o := range options {
+		o(p)
+	}
+
+	return p
+}
+
+// WithPipefail enables pipefail semantics for the exit code of the pipeline
+func WithPipefail(p *Pipeline) {
+	p.Pipefail = true
+}
+
+// WithPipelineTimeout sets a timeout which is shared by all commands of the pipeline
+func WithPipelineTimeout(t time.Duration) func(p *Pipeline) {
+	return func(p *Pipeline) {
+		p.Timeout = t
+	}
+}
+
+// Stdout returns the output to stdout of the last command
+func (p *Pipeline) Stdout() string {
+	p.isExecuted("Stdout")
+	return p.Commands[len(p.Commands)-1].stdout.String()
+}
+
+// ExitCode returns the exit code of the last command, or if pipefail
+// is enabled, the exit code of the last command which exited with an
+// unexpected exit code
+func (p *Pipeline) ExitCode() int {
+	p.isExecuted("ExitCode")
+	if p.Pipefail {
+		for i := len(p.Commands) - 1; i >= 0; i-- {
+			if !p.Commands[i].isExpectedExitCode(p.Commands[i].exitCode) {
+				return p.Commands[i].exitCode
+			}
+		}
+	}
+	return p.Commands[len(p.Commands)-1].exitCode
+}
+
+// Executed returns if the pipeline was already executed
+func (p *Pipeline) Executed() bool {
+	return p.executed
+}
+
+func (p *Pipeline) isExecuted(property string) {
+	if !p.executed {
+		panic("Can not read " + property + " if pipeline was not executed.")
+	}
+}
+
+// ExecuteContext runs Execute but with Context
+func (p *Pipeline) ExecuteContext(ctx context.Context) error {
+	if len(p.Commands) == 0 {
+		return errors.New("pipeline has no commands")
+	}
+
+	_, hasDeadline := ctx.Deadline()
+	if p.Timeout > 0 && !hasDeadline {
+		subCtx, cancel := context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+		ctx = subCtx
+	}
+
+	// A failing stage stops the whole pipeline
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	readers := make([]*io.PipeReader, len(p.Commands))
+	writers := make([]*io.PipeWriter, len(p.Commands))
+	for i := 0; i < len(p.Commands)-1; i++ {
+		r, w := io.Pipe()
+		p.Commands[i].StdoutWriter = io.MultiWriter(p.Commands[i].StdoutWriter, w)
+		p.Commands[i+1].StdinReader = r
+		writers[i] = w
+		readers[i+1] = r
+	}
+
+	errs := make([]error, len(p.Commands))
+	var wg sync.WaitGroup
+	for i, c := range p.Commands {
+		wg.Add(1)
+		go func(i int, c *Command) {
+			defer wg.Done()
+			errs[i] = c.ExecuteContext(ctx)
+			if errs[i] != nil {
+				cancel()
+			}
+
+			// Signal EOF to the next stage and stop the previous stage
+			// from writing into a pipe nobody reads anymore
+			if writers[i] != nil {
+				writers[i].Close()
+			}
+			if readers[i] != nil {
+				readers[i].CloseWithError(io.ErrClosedPipe)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	p.executed = true
+
+	if p.Timeout > 0 && !hasDeadline && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("pipeline timed out after %v", p.Timeout)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Execute executes all commands of the pipeline and writes the results into their own instances
+func (p *Pipeline) Execute() error {
+	return p.ExecuteContext(context.Background())
+}
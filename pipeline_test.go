@@ -0,0 +1,100 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline_Execute(t *testing.T) {
+	p := NewPipeline([]*Command{
+		NewCommand("printf 'b\\na\\nc\\n'"),
+		NewCommand("sort"),
+		NewCommand("head -n 2"),
+	})
+
+	err := p.Execute()
+
+	assert.Nil(t, err)
+	assert.True(t, p.Executed())
+	assert.Equal(t, "a\nb\n", p.Stdout())
+	assert.Equal(t, 0, p.ExitCode())
+}
+
+func TestPipeline_StageResults(t *testing.T) {
+	p := NewPipeline([]*Command{
+		NewCommand(">&2 echo first; echo hello; exit 3"),
+		NewCommand("cat"),
+	})
+
+	err := p.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, p.Commands[0].ExitCode())
+	assert.Equal(t, "first\n", p.Commands[0].Stderr())
+	assert.Equal(t, "hello\n", p.Stdout())
+	assert.Equal(t, 0, p.ExitCode())
+}
+
+func TestPipeline_WithPipefail(t *testing.T) {
+	p := NewPipeline([]*Command{
+		NewCommand("exit 2"),
+		NewCommand("exit 4"),
+		NewCommand("cat"),
+	}, WithPipefail)
+
+	err := p.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, p.ExitCode())
+}
+
+func TestPipeline_WithPipefailExpectedExitCodes(t *testing.T) {
+	p := NewPipeline([]*Command{
+		NewCommand("exit 2"),
+		NewCommand("grep x", WithExpectedExitCodes(0, 1)),
+		NewCommand("cat"),
+	}, WithPipefail)
+
+	err := p.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, p.Commands[1].ExitCode())
+	assert.Equal(t, 2, p.ExitCode())
+}
+
+func TestPipeline_ReaderExitsEarly(t *testing.T) {
+	p := NewPipeline([]*Command{
+		NewCommand("yes"),
+		NewCommand("head -n 1"),
+	}, WithPipelineTimeout(5*time.Second))
+
+	err := p.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "y\n", p.Stdout())
+	assert.Equal(t, 0, p.ExitCode())
+}
+
+func TestPipeline_WithPipelineTimeout(t *testing.T) {
+	p := NewPipeline([]*Command{
+		NewCommand("sleep 1"),
+		NewCommand("cat"),
+	}, WithPipelineTimeout(50*time.Millisecond))
+
+	err := p.Execute()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "pipeline timed out after 50ms", err.Error())
+}
+
+func TestPipeline_WithoutCommands(t *testing.T) {
+	p := NewPipeline(nil)
+
+	err := p.Execute()
+
+	assert.EqualError(t, err, "pipeline has no commands")
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// ExecutableError is returned by Validate if the executable of a command can not be resolved
+type ExecutableError struct {
+	Name string
+	// Root is the root dir of the command if it is executed in a chroot, see WithChroot
+	Root string
+	// Err is exec.ErrNotFound if the executable was not found in PATH
+	Err error
+}
+
+func (e *ExecutableError) Error() string {
+	if e.Root != "" {
+		return fmt.Sprintf("executable %q in chroot %q: %v", e.Name, e.Root, e.Err)
+	}
+	return fmt.Sprintf("executable %q: %v", e.Name, e.Err)
+}
+
+func (e *ExecutableError) Unwrap() error {
+	return e.Err
+}
+
+// WorkingDirError is returned by Validate if the working dir is invalid
+type WorkingDirError struct {
+	Dir string
+	Err error
+}
+
+func (e *WorkingDirError) Error() string {
+	return fmt.Sprintf("working dir %q: %v", e.Dir, e.Err)
+}
+
+func (e *WorkingDirError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNotADirectory is returned by Validate if the working dir is not a directory
+var ErrNotADirectory = errors.New("not a directory")
+
+// WithPreflight validates the command before it is started, see Validate
+//
+// Example:
+//
+//	c := cmd.NewCommand("terraform apply", cmd.WithPreflight)
+//	err := c.Execute()
+//	if errors.Is(err, exec.ErrNotFound) {
+//	    fmt.Println("please install terraform")
+//	}
+func WithPreflight(c *Command) {
+	c.preflight = true
+}
+
+// Validate checks that the command can be started without starting it
+// It returns errors of options, checks that the working dir exists and
+// resolves the executable against the PATH of the command, or the PATH of the
+// current process if the command has none. For shell commands the first word
+// is resolved, shell builtins and words containing variables are skipped.
+// If the command is executed in a chroot all paths are resolved in the new root.
+func (c *Command) Validate() error {
+	return c.validate(c.WorkingDir)
+}
+
+// validate validates the command with the working dir of the execution,
+// which differs from WorkingDir if the command has a temporary working dir
+func (c *Command) validate(workingDir string) error {
+	if c.optionErr != nil {
+		return c.optionErr
+	}
+
+	if err := c.validateChroot(); err != nil {
+		return err
+	}
+
+	if workingDir != "" {
+		dir, err := c.inRoot(workingDir)
+		if err != nil {
+			return &WorkingDirError{Dir: workingDir, Err: err}
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return &WorkingDirError{Dir: workingDir, Err: errors.Unwrap(err)}
+		}
+		if !info.IsDir() {
+			return &WorkingDirError{Dir: workingDir, Err: ErrNotADirectory}
+		}
+	}
+
+	name := c.executable()
+	if name == "" {
+		return nil
+	}
+
+	pathEnv, ok := c.Environment().Get("PATH")
+	if !ok {
+		pathEnv = os.Getenv("PATH")
+	}
+	dir := workingDir
+	if c.chroot != "" {
+		// Paths are resolved in the root, relative to the working dir in the root
+		dir = path.Join("/", dir)
+	}
+	if _, err := lookPath(name, pathEnv, dir, c.chroot); err != nil {
+		return &ExecutableError{Name: name, Root: c.chroot, Err: err}
+	}
+	return nil
+}
+
+// validateChroot checks that the program of the base command, e.g. /bin/sh,
+// exists in the root of a command which is executed in a chroot
+func (c *Command) validateChroot() error {
+	if c.chroot == "" || c.baseCommand.Path == "" {
+		return nil
+	}
+
+	if _, err := resolveExecutable(c.baseCommand.Path, "", c.chroot); err != nil {
+		return &ExecutableError{Name: c.baseCommand.Path, Root: c.chroot, Err: err}
+	}
+	return nil
+}
+
+// inRoot returns the path of a path in the root of a command which is executed
+// in a chroot, relative paths are relative to the root, see resolveInRoot
+func (c *Command) inRoot(path string) (string, error) {
+	if c.chroot == "" {
+		return path, nil
+	}
+	return resolveInRoot(c.chroot, path)
+}
+
+// maxSymlinks is the number of symlinks which are followed when resolving a path, like on linux
+const maxSymlinks = 40
+
+// resolveInRoot returns the path of a path in the root with all symlinks resolved
+// in the root, e.g. a link /bin/sh -> /bin/dash in the root points to root/bin/dash
+// and not to /bin/dash of the current process. Paths can not leave the root with "..".
+func resolveInRoot(root, name string) (string, error) {
+	resolved := "/"
+	rest := strings.Split(filepath.ToSlash(name), "/")
+	links := 0
+	for len(rest) > 0 {
+		part := rest[0]
+		rest = rest[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			// Missing files are reported by the caller
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", syscall.ELOOP
+		}
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return filepath.Join(root, resolved), nil
+}
+
+// executable returns the name of the program which is executed by the command,
+// or an empty string if it can not be determined
+func (c *Command) executable() string {
+	if c.Args != nil {
+		if len(c.Args) == 0 {
+			return ""
+		}
+		return c.Args[0]
+	}
+
+	skipTarget := false
+	for _, word := range strings.FieldsFunc(c.Command, isShellSeparator) {
+		switch {
+		case skipTarget:
+			skipTarget = false
+			continue
+		case isRedirection(word):
+			// The target of `> file` is a separate word
+			skipTarget = strings.HasSuffix(word, ">") || strings.HasSuffix(word, "<")
+			continue
+		case isAssignment(word):
+			continue
+		case strings.ContainsAny(word, "$`*?%"), isShellBuiltin(word):
+			return ""
+		}
+		return strings.Trim(word, `"'`)
+	}
+	return ""
+}
+
+func isShellSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == ';' || r == '&' || r == '|' || r == '(' || r == ')'
+}
+
+// isRedirection reports if a word redirects a stream, e.g. >&2, 2>/dev/null or <input
+func isRedirection(word string) bool {
+	return strings.IndexAny(strings.TrimLeft(word, "0123456789"), "<>") == 0
+}
+
+// isAssignment reports if a word assigns a variable, e.g. FOO=bar
+func isAssignment(word string) bool {
+	key, _, ok := strings.Cut(word, "=")
+	return ok && isEnvKey(key)
+}
+
+// resolveExecutable checks that a path is an executable file,
+// if root is set the path is resolved in the root, see resolveInRoot
+func resolveExecutable(file, dir, root string) (string, error) {
+	path := file
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+	if root != "" {
+		var err error
+		if path, err = resolveInRoot(root, path); err != nil {
+			return "", fs.ErrNotExist
+		}
+	}
+	if err := isExecutable(path); err != nil {
+		return "", err
+	}
+	return file, nil
+}
@@ -0,0 +1,108 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_Validate(t *testing.T) {
+	tests := []string{
+		"echo hello",
+		"ls -la",
+		"exit 1",
+		">&2 echo hello",
+		"2> /dev/null ls",
+		"FOO=bar ls",
+		"$SHELL -c true",
+		"if true; then ls; fi",
+		"(cd /tmp && ls)",
+	}
+
+	for _, command := range tests {
+		t.Run(command, func(t *testing.T) {
+			c := NewCommand(command)
+			assert.Nil(t, c.Validate())
+		})
+	}
+}
+
+func TestCommand_ValidateExecutableNotFound(t *testing.T) {
+	c := NewCommand("FOO=bar cmd-does-not-exist --help | cat")
+
+	err := c.Validate()
+
+	var execErr *ExecutableError
+	require.True(t, errors.As(err, &execErr))
+	assert.Equal(t, "cmd-does-not-exist", execErr.Name)
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+	assert.EqualError(t, err, `executable "cmd-does-not-exist": executable file not found in $PATH`)
+}
+
+func TestCommand_ValidateUsesPathOfCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "custom-tool")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho custom\n"), 0755))
+
+	c := NewCommand("custom-tool")
+	assert.ErrorIs(t, c.Validate(), exec.ErrNotFound)
+
+	c = NewCommand("custom-tool", WithEnvironmentVariables(EnvVars{"PATH": dir}))
+	assert.Nil(t, c.Validate())
+
+	c = NewCommandArgs([]string{"custom-tool"}, WithEnvironmentVariables(EnvVars{"PATH": "/usr/bin:" + dir}))
+	assert.Nil(t, c.Validate())
+}
+
+func TestCommand_ValidateRelativeToWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.txt"), []byte("data"), 0644))
+
+	c := NewCommand("./run.sh", WithWorkingDir(dir))
+	assert.Nil(t, c.Validate())
+
+	c = NewCommand("./missing.sh", WithWorkingDir(dir))
+	assert.ErrorIs(t, c.Validate(), fs.ErrNotExist)
+
+	c = NewCommand("./data.txt", WithWorkingDir(dir))
+	assert.ErrorIs(t, c.Validate(), fs.ErrPermission)
+}
+
+func TestCommand_ValidateWorkingDir(t *testing.T) {
+	c := NewCommand("ls", WithWorkingDir("/invalid/dir"))
+	err := c.Validate()
+
+	var dirErr *WorkingDirError
+	require.True(t, errors.As(err, &dirErr))
+	assert.Equal(t, "/invalid/dir", dirErr.Dir)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	c = NewCommand("ls", WithWorkingDir(file))
+	assert.ErrorIs(t, c.Validate(), ErrNotADirectory)
+}
+
+func TestCommand_ValidateOptionErrors(t *testing.T) {
+	c := NewCommand("ls", WithEnvFile("/invalid/.env"))
+
+	assert.ErrorIs(t, c.Validate(), fs.ErrNotExist)
+}
+
+func TestCommand_WithPreflight(t *testing.T) {
+	c := NewCommand("cmd-does-not-exist", WithPreflight)
+
+	err := c.Execute()
+
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+	assert.False(t, c.Executed())
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// shellBuiltins are commands and keywords of /bin/sh which are not looked up in PATH
+var shellBuiltins = map[string]bool{
+	"!": true, "{": true, ".": true, ":": true, "[": true, "alias": true, "bg": true,
+	"break": true, "case": true, "cd": true, "command": true, "continue": true,
+	"echo": true, "eval": true, "exec": true, "exit": true, "export": true,
+	"false": true, "fg": true, "for": true, "getopts": true, "hash": true, "if": true,
+	"jobs": true, "kill": true, "local": true, "printf": true, "pwd": true, "read": true,
+	"readonly": true, "return": true, "set": true, "shift": true, "test": true,
+	"times": true, "trap": true, "true": true, "type": true, "ulimit": true,
+	"umask": true, "unalias": true, "unset": true, "until": true, "wait": true, "while": true,
+}
+
+func isShellBuiltin(word string) bool {
+	return shellBuiltins[word]
+}
+
+// lookPath searches an executable like exec.LookPath, but in the given PATH
+// and relative to the given working dir, if root is set paths are resolved in the root
+func lookPath(file, pathEnv, dir, root string) (string, error) {
+	if strings.Contains(file, "/") {
+		return resolveExecutable(file, dir, root)
+	}
+
+	for _, d := range filepath.SplitList(pathEnv) {
+		if d == "" {
+			d = "."
+		}
+		if path, err := resolveExecutable(filepath.Join(d, file), dir, root); err == nil {
+			return path, nil
+		}
+	}
+	return "", exec.ErrNotFound
+}
+
+func isExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fs.ErrNotExist
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fs.ErrPermission
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// shellBuiltins are commands of cmd.exe which are not looked up in PATH
+var shellBuiltins = map[string]bool{
+	"assoc": true, "break": true, "call": true, "cd": true, "chdir": true, "cls": true,
+	"color": true, "copy": true, "date": true, "del": true, "dir": true, "echo": true,
+	"endlocal": true, "erase": true, "exit": true, "for": true, "ftype": true, "goto": true,
+	"if": true, "md": true, "mkdir": true, "mklink": true, "move": true, "path": true,
+	"pause": true, "popd": true, "prompt": true, "pushd": true, "rd": true, "rem": true,
+	"ren": true, "rename": true, "rmdir": true, "set": true, "setlocal": true, "shift": true,
+	"start": true, "time": true, "title": true, "type": true, "ver": true, "verify": true, "vol": true,
+}
+
+func isShellBuiltin(word string) bool {
+	return shellBuiltins[strings.ToLower(word)]
+}
+
+// lookPath searches an executable like exec.LookPath, but in the given PATH
+// and relative to the given working dir, if root is set paths are resolved in the root
+func lookPath(file, pathEnv, dir, root string) (string, error) {
+	if strings.ContainsAny(file, `:\/`) {
+		return resolveWithExtension(file, dir, root)
+	}
+
+	// cmd.exe searches the working dir before PATH
+	dirs := append([]string{"."}, filepath.SplitList(pathEnv)...)
+	for _, d := range dirs {
+		if path, err := resolveWithExtension(filepath.Join(d, file), dir, root); err == nil {
+			return path, nil
+		}
+	}
+	return "", exec.ErrNotFound
+}
+
+func resolveWithExtension(file, dir, root string) (string, error) {
+	if filepath.Ext(file) != "" {
+		return resolveExecutable(file, dir, root)
+	}
+
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".com;.exe;.bat;.cmd"
+	}
+	for _, ext := range filepath.SplitList(exts) {
+		if path, err := resolveExecutable(file+strings.ToLower(ext), dir, root); err == nil {
+			return path, nil
+		}
+	}
+	return "", fs.ErrNotExist
+}
+
+func isExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fs.ErrNotExist
+	}
+	if info.IsDir() {
+		return fs.ErrPermission
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// IOClass is the I/O scheduling class of a command, see WithIOPriority
+type IOClass int
+
+const (
+	// IOClassRealtime is served before all other classes, it requires CAP_SYS_ADMIN
+	IOClassRealtime IOClass = 1
+	// IOClassBestEffort is the default class of processes
+	IOClassBestEffort IOClass = 2
+	// IOClassIdle is only served if no other process needs the disk
+	IOClassIdle IOClass = 3
+)
+
+const (
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// WithNice sets the nice value of the command, from -20 (highest priority)
+// to 19 (lowest priority). Negative values require CAP_SYS_NICE.
+// The command is started by a thread with the nice value, which it inherits.
+//
+// Example:
+//
+//	c := cmd.NewCommand("./backup.sh", cmd.WithNice(10), cmd.WithIOPriority(cmd.IOClassIdle, 0))
+//	c.Execute()
+func WithNice(n int) func(c *Command) {
+	return func(c *Command) {
+		if n < -20 || n > 19 {
+			c.addOptionError(fmt.Errorf("invalid nice value %d, must be between -20 and 19", n))
+			return
+		}
+		c.nice = &n
+	}
+}
+
+// WithIOPriority sets the I/O scheduling class and the priority level in the class of the command,
+// from 0 (highest priority) to 7 (lowest priority). The level of IOClassIdle must be 0.
+// The command is started by a thread with the priority, which it inherits.
+func WithIOPriority(class IOClass, level int) func(c *Command) {
+	return func(c *Command) {
+		switch {
+		case class < IOClassRealtime || class > IOClassIdle:
+			c.addOptionError(fmt.Errorf("invalid I/O class %d", class))
+			return
+		case class == IOClassIdle && level != 0:
+			c.addOptionError(fmt.Errorf("invalid I/O priority level %d, the idle class has no levels", level))
+			return
+		case level < 0 || level > 7:
+			c.addOptionError(fmt.Errorf("invalid I/O priority level %d, must be between 0 and 7", level))
+			return
+		}
+
+		c.ioPriority = int(class)<<ioprioClassShift | level
+	}
+}
+
+// setNice sets the nice value of the current thread
+func setNice(n int) error {
+	// On linux the nice value of PRIO_PROCESS 0 is the value of the calling thread
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, n); err != nil {
+		return fmt.Errorf("can not set nice value: %w", err)
+	}
+	return nil
+}
+
+// setIOPriority sets the I/O priority of the current thread
+func setIOPriority(prio int) error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(prio))
+	if errno != 0 {
+		return fmt.Errorf("can not set I/O priority: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// niceOfShell prints the nice value of the shell, the 19th field of /proc/<pid>/stat
+// The fields are counted after the command name which may contain spaces.
+const niceOfShell = `cut -d')' -f2 /proc/$$/stat | cut -d' ' -f18`
+
+func TestCommand_WithNice(t *testing.T) {
+	c := NewCommand(niceOfShell, WithNice(10))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "10\n", c.Stdout(), c.Stderr())
+}
+
+func TestCommand_WithNiceNegative(t *testing.T) {
+	c := NewCommand(niceOfShell, WithNice(-5))
+	err := c.Execute()
+
+	if err != nil {
+		assert.ErrorContains(t, err, "can not set nice value")
+		t.Skipf("negative nice values require CAP_SYS_NICE: %v", err)
+	}
+	assert.Equal(t, "-5\n", c.Stdout(), c.Stderr())
+}
+
+func TestCommand_WithNiceIsInherited(t *testing.T) {
+	// cut reads its own stat, it is started by the shell
+	c := NewCommand(strings.Replace(niceOfShell, "$$", "self", 1), WithNice(19))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "19\n", c.Stdout(), c.Stderr())
+}
+
+func TestCommand_WithIOPriority(t *testing.T) {
+	if _, err := exec.LookPath("ionice"); err != nil {
+		t.Skip("ionice is not installed")
+	}
+
+	tests := []struct {
+		name     string
+		class    IOClass
+		level    int
+		expected string
+	}{
+		{"best effort", IOClassBestEffort, 7, "best-effort: prio 7"},
+		{"idle", IOClassIdle, 0, "idle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCommand("ionice -p $$", WithIOPriority(tt.class, tt.level))
+			err := c.Execute()
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, strings.TrimSpace(c.Stdout()), c.Stderr())
+		})
+	}
+}
+
+func TestCommand_WithNiceAndIOPriority(t *testing.T) {
+	c := NewCommand(niceOfShell, WithNice(5), WithIOPriority(IOClassIdle, 0), WithResourceLimits(Limits{OpenFiles: 64}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, "5\n", c.Stdout(), c.Stderr())
+}
+
+func TestCommand_WithPriorityInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(*Command)
+		err    string
+	}{
+		{"nice too low", WithNice(-21), "invalid nice value -21, must be between -20 and 19"},
+		{"nice too high", WithNice(20), "invalid nice value 20, must be between -20 and 19"},
+		{"io class", WithIOPriority(IOClass(4), 0), "invalid I/O class 4"},
+		{"io level", WithIOPriority(IOClassBestEffort, 8), "invalid I/O priority level 8, must be between 0 and 7"},
+		{"negative io level", WithIOPriority(IOClassRealtime, -1), "invalid I/O priority level -1, must be between 0 and 7"},
+		{"idle level", WithIOPriority(IOClassIdle, 3), "invalid I/O priority level 3, the idle class has no levels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCommand("echo hello", tt.option)
+			err := c.Execute()
+
+			assert.EqualError(t, err, tt.err)
+			assert.False(t, c.Executed())
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Capability is a linux capability, see capabilities(7)
+type Capability uintptr
+
+// Capabilities which can be passed to WithCapabilities
+const (
+	CapChown             Capability = 0
+	CapDacOverride       Capability = 1
+	CapDacReadSearch     Capability = 2
+	CapFowner            Capability = 3
+	CapFsetid            Capability = 4
+	CapKill              Capability = 5
+	CapSetgid            Capability = 6
+	CapSetuid            Capability = 7
+	CapSetpcap           Capability = 8
+	CapLinuxImmutable    Capability = 9
+	CapNetBindService    Capability = 10
+	CapNetBroadcast      Capability = 11
+	CapNetAdmin          Capability = 12
+	CapNetRaw            Capability = 13
+	CapIpcLock           Capability = 14
+	CapIpcOwner          Capability = 15
+	CapSysModule         Capability = 16
+	CapSysRawio          Capability = 17
+	CapSysChroot         Capability = 18
+	CapSysPtrace         Capability = 19
+	CapSysPacct          Capability = 20
+	CapSysAdmin          Capability = 21
+	CapSysBoot           Capability = 22
+	CapSysNice           Capability = 23
+	CapSysResource       Capability = 24
+	CapSysTime           Capability = 25
+	CapSysTtyConfig      Capability = 26
+	CapMknod             Capability = 27
+	CapLease             Capability = 28
+	CapAuditWrite        Capability = 29
+	CapAuditControl      Capability = 30
+	CapSetfcap           Capability = 31
+	CapMacOverride       Capability = 32
+	CapMacAdmin          Capability = 33
+	CapSyslog            Capability = 34
+	CapWakeAlarm         Capability = 35
+	CapBlockSuspend      Capability = 36
+	CapAuditRead         Capability = 37
+	CapPerfmon           Capability = 38
+	CapBpf               Capability = 39
+	CapCheckpointRestore Capability = 40
+	capLast                         = CapCheckpointRestore
+)
+
+const (
+	prSetNoNewPrivs         = 38
+	prCapAmbient            = 47
+	prCapAmbientClearAll    = 4
+	secbitNoRoot            = 1 << 0
+	secbitNoRootLocked      = 1 << 1
+	linuxCapabilityVersion3 = 0x20080522
+)
+
+// capHeader and capData are the arguments of capget(2) and capset(2)
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+// WithCapabilities restricts the capabilities of the command to the allowlist
+// The capabilities are added to the ambient and inheritable set, so they are
+// kept if the command runs as another user, see WithUser, and are inherited by
+// programs executed by the command. All other capabilities are dropped.
+// Without capabilities the command runs without any capabilities.
+//
+// The command is started by a thread without ambient and inheritable capabilities,
+// so capabilities which the current process has in these sets are not passed on.
+// Commands which run as root are started by a thread on which root is not
+// granted all capabilities on exec, which requires CAP_SETPCAP.
+// The capabilities must be permitted for the current process.
+//
+// Example:
+//
+//	c := cmd.NewCommand("./server --port 80",
+//	    cmd.WithUsername("www-data"),
+//	    cmd.WithCapabilities(cmd.CapNetBindService),
+//	    cmd.WithNoNewPrivileges,
+//	)
+//	c.Execute()
+func WithCapabilities(caps ...Capability) func(c *Command) {
+	return func(c *Command) {
+		ambient := make([]uintptr, 0, len(caps))
+		for _, capability := range caps {
+			if capability > capLast {
+				c.addOptionError(fmt.Errorf("invalid capability %d", capability))
+				return
+			}
+			ambient = append(ambient, uintptr(capability))
+		}
+
+		c.restrictCaps = true
+		WithSysProcAttr(func(attr *syscall.SysProcAttr) {
+			attr.AmbientCaps = ambient
+		})(c)
+	}
+}
+
+// WithNoNewPrivileges prevents the command and its children from gaining privileges on exec,
+// e.g. by set-user-ID binaries like sudo or by file capabilities.
+// The command is started by a thread on which PR_SET_NO_NEW_PRIVS is set.
+func WithNoNewPrivileges(c *Command) {
+	c.noNewPrivs = true
+}
+
+// runsAsRoot reports if the process runs as root, in a user namespace root is mapped
+// to the current user by WithNamespaces
+func runsAsRoot(attr *syscall.SysProcAttr) bool {
+	if attr == nil {
+		return os.Geteuid() == 0
+	}
+	if attr.Credential != nil {
+		return attr.Credential.Uid == 0
+	}
+	return os.Geteuid() == 0 || attr.Cloneflags&syscall.CLONE_NEWUSER != 0
+}
+
+// threadAttrs are attributes of a thread which are inherited by the processes it starts
+type threadAttrs struct {
+	noNewPrivs bool
+	clearCaps  bool
+	noRoot     bool
+	setNice    bool
+	nice       int
+	ioPriority int
+}
+
+var (
+	threadsMu sync.Mutex
+	// threads execute the functions which are sent to them on a thread with the attributes
+	threads = map[threadAttrs]chan func(){}
+)
+
+// onThread executes fn on a thread with the attributes
+// The attributes can not be reset, so the threads are locked and kept running.
+// This keeps the parent death signal of the started processes, see WithParentDeathSignal.
+func onThread(attrs threadAttrs, fn func() error) error {
+	threadsMu.Lock()
+	funcs, ok := threads[attrs]
+	if !ok {
+		funcs = make(chan func())
+		started := make(chan error)
+		go runThread(attrs, funcs, started)
+		if err := <-started; err != nil {
+			threadsMu.Unlock()
+			return err
+		}
+		threads[attrs] = funcs
+	}
+	threadsMu.Unlock()
+
+	done := make(chan error, 1)
+	funcs <- func() { done <- fn() }
+	return <-done
+}
+
+func runThread(attrs threadAttrs, funcs chan func(), started chan error) {
+	// The thread is terminated if the goroutine returns without unlocking it
+	runtime.LockOSThread()
+
+	if err := attrs.apply(); err != nil {
+		started <- err
+		return
+	}
+	started <- nil
+
+	for fn := range funcs {
+		fn()
+	}
+}
+
+// apply sets the attributes on the current thread
+func (a threadAttrs) apply() error {
+	if a.noNewPrivs {
+		if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+			return fmt.Errorf("can not set no new privileges: %w", errno)
+		}
+	}
+
+	if a.clearCaps {
+		if err := clearInheritableCaps(); err != nil {
+			return fmt.Errorf("can not clear capabilities: %w", err)
+		}
+	}
+
+	if a.noRoot {
+		bits, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, syscall.PR_GET_SECUREBITS, 0, 0, 0, 0, 0)
+		if errno == 0 {
+			bits |= secbitNoRoot | secbitNoRootLocked
+			_, _, errno = syscall.RawSyscall6(syscall.SYS_PRCTL, syscall.PR_SET_SECUREBITS, bits, 0, 0, 0, 0)
+		}
+		if errno != 0 {
+			return fmt.Errorf("can not restrict capabilities of root: %w", errno)
+		}
+	}
+
+	if a.setNice {
+		if err := setNice(a.nice); err != nil {
+			return err
+		}
+	}
+	if a.ioPriority != 0 {
+		if err := setIOPriority(a.ioPriority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearInheritableCaps clears the ambient and inheritable capabilities of the current thread
+// Starting a process only raises the ambient capabilities of SysProcAttr.AmbientCaps,
+// capabilities which are already set would be kept otherwise.
+func clearInheritableCaps() error {
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientClearAll, 0, 0, 0, 0); errno != 0 {
+		return errno
+	}
+
+	header := capHeader{version: linuxCapabilityVersion3}
+	var data [2]capData
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPGET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return errno
+	}
+	data[0].inheritable, data[1].inheritable = 0, 0
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
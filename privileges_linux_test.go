@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// processStatus returns the values of the keys of /proc/self/status of a command
+func processStatus(t *testing.T, c *Command) map[string]string {
+	err := c.Execute()
+	require.NoError(t, err)
+	require.Equal(t, 0, c.ExitCode(), c.Stderr())
+
+	status := map[string]string{}
+	for _, line := range strings.Split(c.Stdout(), "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			status[key] = strings.TrimSpace(value)
+		}
+	}
+	return status
+}
+
+func skipWithoutRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("restricting capabilities requires root")
+	}
+}
+
+func TestCommand_WithCapabilities(t *testing.T) {
+	skipWithoutRoot(t)
+
+	status := processStatus(t, NewCommand("cat /proc/self/status", WithCapabilities(CapChown, CapNetBindService)))
+
+	assert.Equal(t, "0000000000000401", status["CapEff"])
+	assert.Equal(t, "0000000000000401", status["CapPrm"])
+	assert.Equal(t, "0000000000000401", status["CapAmb"])
+	assert.Equal(t, "0000000000000401", status["CapInh"])
+}
+
+func TestCommand_WithCapabilitiesWithUser(t *testing.T) {
+	skipWithoutRoot(t)
+
+	nobody := syscall.Credential{Uid: 65534, Gid: 65534}
+	status := processStatus(t, NewCommand("cat /proc/self/status", WithUser(nobody), WithCapabilities(CapNetBindService)))
+
+	assert.Equal(t, "65534\t65534\t65534\t65534", status["Uid"])
+	assert.Equal(t, "0000000000000400", status["CapEff"])
+	assert.Equal(t, "0000000000000400", status["CapPrm"])
+	assert.Equal(t, "0000000000000400", status["CapAmb"])
+}
+
+func TestCommand_WithoutCapabilities(t *testing.T) {
+	skipWithoutRoot(t)
+
+	status := processStatus(t, NewCommand("cat /proc/self/status", WithCapabilities()))
+	assert.Equal(t, "0000000000000000", status["CapEff"])
+	assert.Equal(t, "0000000000000000", status["CapPrm"])
+	assert.Equal(t, "0", strings.Fields(status["Uid"])[0])
+
+	status = processStatus(t, NewCommand("cat /proc/self/status"))
+	assert.NotEqual(t, "0000000000000000", status["CapEff"])
+}
+
+// TestCapabilitiesHelper is executed as a separate process with ambient capabilities
+// by TestCommand_WithCapabilitiesClearsInherited. It prints its own capabilities
+// and the capabilities of a command started with an allowlist.
+func TestCapabilitiesHelper(t *testing.T) {
+	if os.Getenv("CMD_TEST_CAPABILITIES") == "" {
+		t.Skip("helper process of TestCommand_WithCapabilitiesClearsInherited")
+	}
+
+	status, err := os.ReadFile("/proc/self/status")
+	require.NoError(t, err)
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, "Cap") {
+			fmt.Println("Helper" + line)
+		}
+	}
+
+	NewCommand("cat /proc/self/status", WithCustomStdout(os.Stdout), WithCapabilities(CapChown)).Execute()
+}
+
+func TestCommand_WithCapabilitiesClearsInherited(t *testing.T) {
+	skipWithoutRoot(t)
+
+	helper := exec.Command(os.Args[0], "-test.run=^TestCapabilitiesHelper$")
+	helper.Env = append(os.Environ(), "CMD_TEST_CAPABILITIES=1")
+	helper.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{uintptr(CapNetRaw), uintptr(CapSysAdmin)},
+	}
+	out, err := helper.Output()
+	require.NoError(t, err, string(out))
+
+	status := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			status[key] = strings.TrimSpace(value)
+		}
+	}
+
+	require.Equal(t, "0000000000202000", status["HelperCapAmb"])
+	require.Equal(t, "0000000000202000", status["HelperCapInh"])
+	assert.Equal(t, "0000000000000001", status["CapAmb"])
+	assert.Equal(t, "0000000000000001", status["CapInh"])
+	assert.Equal(t, "0000000000000001", status["CapEff"])
+	assert.Equal(t, "0000000000000001", status["CapPrm"])
+}
+
+func TestCommand_WithCapabilitiesInvalid(t *testing.T) {
+	c := NewCommand("echo hello", WithCapabilities(CapChown, Capability(64)))
+	err := c.Execute()
+
+	assert.EqualError(t, err, "invalid capability 64")
+	assert.False(t, c.Executed())
+}
+
+func TestCommand_WithNoNewPrivileges(t *testing.T) {
+	status := processStatus(t, NewCommand("cat /proc/self/status", WithNoNewPrivileges))
+	assert.Equal(t, "1", status["NoNewPrivs"])
+
+	// The flag is only set on the thread which starts the command
+	status = processStatus(t, NewCommand("cat /proc/self/status"))
+	assert.Equal(t, "0", status["NoNewPrivs"])
+}
+
+func TestCommand_WithNoNewPrivilegesAndOtherOptions(t *testing.T) {
+	skipWithoutRoot(t)
+
+	nobody := syscall.Credential{Uid: 65534, Gid: 65534}
+	c := NewCommand("cat /proc/self/status",
+		WithNoNewPrivileges,
+		WithUser(nobody),
+		WithCapabilities(CapNetBindService),
+		WithNice(5),
+		WithParentDeathSignal(syscall.SIGKILL),
+	)
+	status := processStatus(t, c)
+
+	assert.Equal(t, "1", status["NoNewPrivs"])
+	assert.Equal(t, "0000000000000400", status["CapEff"])
+	assert.Equal(t, "65534\t65534\t65534\t65534", status["Uid"])
+}
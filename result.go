@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// RedactedValue replaces the values of env variables in a redacted Result
+const RedactedValue = "[REDACTED]"
+
+// Result is a snapshot of the configuration and the results of a command
+// It is used as the JSON representation of a Command.
+type Result struct {
+	Command           string        `json:"command"`
+	Args              []string      `json:"args,omitempty"`
+	Env               []string      `json:"env"`
+	WorkingDir        string        `json:"working_dir,omitempty"`
+	Timeout           time.Duration `json:"timeout_ns"`
+	ExpectedExitCodes []int         `json:"expected_exit_codes,omitempty"`
+	Executed          bool          `json:"executed"`
+	ExitCode          int           `json:"exit_code"`
+	Stdout            string        `json:"stdout"`
+	Stderr            string        `json:"stderr"`
+	Combined          string        `json:"combined"`
+	StartedAt         time.Time     `json:"started_at"`
+	FinishedAt        time.Time     `json:"finished_at"`
+	Duration          time.Duration `json:"duration_ns"`
+	Error             string        `json:"error,omitempty"`
+	Cgroup            *CgroupStats  `json:"cgroup,omitempty"`
+}
+
+// WithRedactedEnv replaces the values of all env variables of a Result
+//
+// Example:
+//
+//	r := c.Result(cmd.WithRedactedEnv)
+func WithRedactedEnv(r *Result) {
+	env := make([]string, len(r.Env))
+	for i, e := range r.Env {
+		key, _ := splitEnv(e)
+		env[i] = key + "=" + RedactedValue
+	}
+	r.Env = env
+}
+
+// Result returns a snapshot of the command
+// It can be called before and after the command was executed, later changes
+// of the command do not change the snapshot.
+func (c *Command) Result(options ...func(*Result)) Result {
+	r := Result{
+		Command:           c.Command,
+		Args:              slices.Clone(c.Args),
+		Env:               slices.Clone(c.Env),
+		WorkingDir:        c.WorkingDir,
+		Timeout:           c.Timeout,
+		ExpectedExitCodes: slices.Clone(c.expectedExitCodes),
+		Executed:          c.executed,
+		ExitCode:          c.exitCode,
+		Stdout:            c.stdout.String(),
+		Stderr:            c.stderr.String(),
+		Combined:          c.combined.String(),
+		StartedAt:         c.startedAt,
+		FinishedAt:        c.finishedAt,
+		Duration:          c.finishedAt.Sub(c.startedAt),
+		Cgroup:            c.CgroupStats(),
+	}
+	if c.err != nil {
+		r.Error = c.err.Error()
+	}
+
+	for _, o := range options {
+		o(&r)
+	}
+
+	return r
+}
+
+// NewCommand creates a new command with the configuration of the snapshot
+// The results of the snapshot are not copied, the new command can be executed again.
+func (r Result) NewCommand(options ...func(*Command)) *Command {
+	c := &Command{}
+	r.configure(c)
+
+	for _, o := range options {
+		o(c)
+	}
+
+	return c
+}
+
+func (r Result) configure(c *Command) {
+	// The snapshot and the command must not share slices
+	c.init(r.Command, slices.Clone(r.Args))
+	if r.Env != nil {
+		c.Env = slices.Clone(r.Env)
+	}
+	c.WorkingDir = r.WorkingDir
+	c.Timeout = r.Timeout
+	c.expectedExitCodes = slices.Clone(r.ExpectedExitCodes)
+}
+
+// MarshalJSON encodes the Result snapshot of the command
+// Use json.Marshal(c.Result(cmd.WithRedactedEnv)) to hide env values.
+func (c *Command) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Result())
+}
+
+// UnmarshalJSON decodes a Result snapshot into the command
+// Only the configuration is restored, the command is not executed
+// afterwards and can be executed again.
+func (c *Command) UnmarshalJSON(data []byte) error {
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+
+	r.configure(c)
+	return nil
+}
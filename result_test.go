@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_Result(t *testing.T) {
+	c := NewCommand("echo hello", WithEnvironmentVariables(EnvVars{"KEY": "secret"}), WithExpectedExitCodes(0, 1))
+
+	r := c.Result()
+	assert.False(t, r.Executed)
+	assert.Equal(t, time.Duration(0), r.Duration)
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	r = c.Result()
+	assert.Equal(t, "echo hello", r.Command)
+	assert.Equal(t, []string{"KEY=secret"}, r.Env)
+	assert.Equal(t, []int{0, 1}, r.ExpectedExitCodes)
+	assert.True(t, r.Executed)
+	assert.Equal(t, 0, r.ExitCode)
+	assertEqualWithLineBreak(t, "hello", r.Stdout)
+	assert.False(t, r.StartedAt.IsZero())
+	assert.Equal(t, r.FinishedAt.Sub(r.StartedAt), r.Duration)
+	assert.Empty(t, r.Error)
+}
+
+func TestCommand_ResultWithError(t *testing.T) {
+	c := NewCommand("echo hello", WithWorkingDir("/invalid/dir"))
+	err := c.Execute()
+
+	r := c.Result()
+	assert.False(t, r.Executed)
+	assert.Equal(t, err.Error(), r.Error)
+}
+
+func TestCommand_ResultWithRedactedEnv(t *testing.T) {
+	c := NewCommand("echo hello", WithEnvironmentVariables(EnvVars{"TOKEN": "secret=value"}))
+
+	r := c.Result(WithRedactedEnv)
+
+	assert.Equal(t, []string{"TOKEN=[REDACTED]"}, r.Env)
+	assert.Equal(t, []string{"TOKEN=secret=value"}, c.Env)
+}
+
+func TestCommand_MarshalJSON(t *testing.T) {
+	c := NewCommandArgs([]string{"echo", "hello"}, WithTimeout(time.Second), WithWorkingDir("/tmp"))
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	expected := `{
+		"command": "echo hello",
+		"args": ["echo", "hello"],
+		"env": [],
+		"working_dir": "/tmp",
+		"timeout_ns": 1000000000,
+		"executed": false,
+		"exit_code": 0,
+		"stdout": "",
+		"stderr": "",
+		"combined": "",
+		"started_at": "0001-01-01T00:00:00Z",
+		"finished_at": "0001-01-01T00:00:00Z",
+		"duration_ns": 0
+	}`
+	assert.JSONEq(t, expected, string(data))
+}
+
+func TestCommand_UnmarshalJSON(t *testing.T) {
+	original := NewCommand("echo $KEY", WithEnvironmentVariables(EnvVars{"KEY": "value"}), WithTimeout(time.Minute))
+	require.NoError(t, original.Execute())
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	var c Command
+	err = json.Unmarshal(data, &c)
+	require.NoError(t, err)
+
+	assert.Equal(t, "echo $KEY", c.Command)
+	assert.Equal(t, []string{"KEY=value"}, c.Env)
+	assert.Equal(t, time.Minute, c.Timeout)
+	assert.False(t, c.Executed())
+
+	err = c.Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, original.Stdout(), c.Stdout())
+}
+
+func TestResult_NewCommand(t *testing.T) {
+	r := Result{Command: "echo hello", Timeout: time.Second, ExitCode: 3, Executed: true}
+
+	c := r.NewCommand(WithExpectedExitCodes(3))
+
+	assert.Equal(t, "echo hello", c.Command)
+	assert.Equal(t, time.Second, c.Timeout)
+	assert.Equal(t, []string{}, c.Env)
+	assert.Equal(t, []int{3}, c.expectedExitCodes)
+	assert.False(t, c.Executed())
+}
+
+func TestCommand_ResultIsNotChangedByCommand(t *testing.T) {
+	c := NewCommandArgs([]string{"echo", "hello"}, WithExpectedExitCodes(0, 1))
+	c.AddEnv("K", "original")
+
+	r := c.Result()
+	c.AddEnv("K", "changed")
+	c.Args[1] = "changed"
+	c.expectedExitCodes[1] = 2
+
+	assert.Equal(t, []string{"K=original"}, r.Env)
+	assert.Equal(t, []string{"echo", "hello"}, r.Args)
+	assert.Equal(t, []int{0, 1}, r.ExpectedExitCodes)
+}
+
+func TestResult_NewCommandDoesNotShareSlices(t *testing.T) {
+	r := Result{Command: "echo", Env: []string{"K=original"}, ExpectedExitCodes: []int{0, 1}}
+
+	c := r.NewCommand()
+	c.AddEnv("K", "changed")
+	c.expectedExitCodes[1] = 2
+
+	assert.Equal(t, []string{"K=original"}, r.Env)
+	assert.Equal(t, []int{0, 1}, r.ExpectedExitCodes)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Limits are resource limits of a command, zero values are not limited
+type Limits struct {
+	// CPUSeconds is the cpu time after which the command receives SIGXCPU,
+	// it is killed with SIGKILL one second later
+	CPUSeconds uint64
+	// AddressSpace is the maximum size of the virtual memory in bytes
+	AddressSpace uint64
+	// OpenFiles is the maximum number of open file descriptors
+	OpenFiles uint64
+	// FileSize is the maximum size of files written by the command in bytes,
+	// writing beyond it sends SIGXFSZ
+	FileSize uint64
+	// Processes is the maximum number of processes of the user the command runs as,
+	// including processes which were not started by the command.
+	// It is not enforced for root.
+	Processes uint64
+}
+
+// WithResourceLimits limits the resources of the command with rlimits
+// The limits are set after exec before the command runs, processes started
+// by the command inherit them. If a limit is hit the command is terminated by
+// a signal, which can be read with Signal, or the system call which exceeded
+// it fails. Setting the limits of a command which runs as another user, see
+// WithUser, requires CAP_SYS_RESOURCE.
+//
+// The command is traced with ptrace(2) until the limits are set, so the command
+// can not be started if the current process is traced itself, e.g. by strace or
+// a debugger, or if ptrace is not permitted by Yama or a seccomp profile.
+//
+// Example:
+//
+//	c := cmd.NewCommand("go test ./...", cmd.WithResourceLimits(cmd.Limits{
+//	    CPUSeconds: 600,
+//	    OpenFiles:  1024,
+//	}))
+//	c.Execute()
+//	if c.Signal() == syscall.SIGXCPU {
+//	    fmt.Println("tests used too much cpu time")
+//	}
+func WithResourceLimits(limits Limits) func(c *Command) {
+	return func(c *Command) {
+		c.beforeRun = append(c.beforeRun, limits.apply)
+	}
+}
+
+// apply sets the limits of the process with the given pid
+func (l Limits) apply(pid int) error {
+	limits := []struct {
+		resource int
+		name     string
+		limit    syscall.Rlimit
+	}{
+		// The hard limit sends SIGKILL instead of SIGXCPU
+		{syscall.RLIMIT_CPU, "cpu", syscall.Rlimit{Cur: l.CPUSeconds, Max: l.CPUSeconds + 1}},
+		{syscall.RLIMIT_AS, "address space", syscall.Rlimit{Cur: l.AddressSpace, Max: l.AddressSpace}},
+		{syscall.RLIMIT_NOFILE, "open files", syscall.Rlimit{Cur: l.OpenFiles, Max: l.OpenFiles}},
+		{syscall.RLIMIT_FSIZE, "file size", syscall.Rlimit{Cur: l.FileSize, Max: l.FileSize}},
+		{rlimitNproc, "processes", syscall.Rlimit{Cur: l.Processes, Max: l.Processes}},
+	}
+
+	for _, rl := range limits {
+		if rl.limit.Cur == 0 {
+			continue
+		}
+		if err := prlimit(pid, rl.resource, &rl.limit); err != nil {
+			return fmt.Errorf("can not set %s limit of process with pid %d: %w", rl.name, pid, err)
+		}
+	}
+	return nil
+}
+
+func prlimit(pid int, resource int, limit *syscall.Rlimit) error {
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource), uintptr(unsafe.Pointer(limit)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_WithResourceLimits(t *testing.T) {
+	c := NewCommand("cat /proc/self/limits", WithResourceLimits(Limits{
+		CPUSeconds:   10,
+		AddressSpace: 1 << 30,
+		OpenFiles:    64,
+		FileSize:     1 << 20,
+		Processes:    100,
+	}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	limits := map[string][]string{}
+	for _, line := range strings.Split(c.Stdout(), "\n") {
+		// Names are padded to 26 characters, followed by the soft and hard limit
+		if len(line) > 26 {
+			limits[strings.TrimSpace(line[:26])] = strings.Fields(line[26:])[:2]
+		}
+	}
+	assert.Equal(t, []string{"10", "11"}, limits["Max cpu time"])
+	assert.Equal(t, []string{"1073741824", "1073741824"}, limits["Max address space"])
+	assert.Equal(t, []string{"64", "64"}, limits["Max open files"])
+	assert.Equal(t, []string{"1048576", "1048576"}, limits["Max file size"])
+	assert.Equal(t, []string{"100", "100"}, limits["Max processes"])
+}
+
+func TestCommand_WithResourceLimitsCPUSeconds(t *testing.T) {
+	c := NewCommand("while :; do :; done", WithResourceLimits(Limits{CPUSeconds: 1}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, -1, c.ExitCode())
+	assert.Equal(t, syscall.SIGXCPU, c.Signal())
+}
+
+func TestCommand_WithResourceLimitsFileSize(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+
+	c := NewCommand("exec head -c 2048 /dev/zero > "+file, WithResourceLimits(Limits{FileSize: 1024}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, syscall.SIGXFSZ, c.Signal())
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), info.Size())
+}
+
+func TestCommand_WithResourceLimitsOpenFiles(t *testing.T) {
+	// stdin, stdout and stderr are open, opening fd 4 exceeds the limit
+	c := NewCommand("cat /dev/null 3</dev/null 4</dev/null", WithResourceLimits(Limits{OpenFiles: 4}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, c.ExitCode())
+	assert.Contains(t, c.Stderr(), "Too many open files")
+
+	c = NewCommand("cat /dev/null 3</dev/null 4</dev/null", WithResourceLimits(Limits{OpenFiles: 16}))
+	err = c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.ExitCode())
+}
+
+func TestCommand_WithResourceLimitsAddressSpace(t *testing.T) {
+	c := NewCommand("/bin/true", WithResourceLimits(Limits{AddressSpace: 1 << 20}))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, c.ExitCode())
+
+	c = NewCommand("/bin/true", WithResourceLimits(Limits{AddressSpace: 1 << 30}))
+	err = c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.ExitCode())
+}
+
+func TestCommand_WithResourceLimitsProcesses(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("processes of another user can only be limited by root")
+	}
+
+	// The limit is not enforced for root, the processes of nobody are counted
+	nobody := syscall.Credential{Uid: 65534, Gid: 65534}
+
+	c := NewCommand("/bin/true && echo started", WithUser(nobody), WithResourceLimits(Limits{Processes: 1}))
+	err := c.Execute()
+
+	if errors.Is(err, syscall.EPERM) {
+		t.Skip("limits of another user can not be set without CAP_SYS_RESOURCE")
+	}
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, c.ExitCode())
+	assert.Empty(t, c.Stdout())
+
+	c = NewCommand("/bin/true && echo started", WithUser(nobody), WithResourceLimits(Limits{Processes: 100}))
+	err = c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.ExitCode())
+	assert.Equal(t, "started\n", c.Stdout())
+}
+
+// TestResourceLimitsTracedHelper is executed as a traced process by TestCommand_WithResourceLimitsTraced
+func TestResourceLimitsTracedHelper(t *testing.T) {
+	if os.Getenv("CMD_TEST_TRACED") == "" {
+		t.Skip("helper process of TestCommand_WithResourceLimitsTraced")
+	}
+
+	err := NewCommand("true", WithResourceLimits(Limits{OpenFiles: 64})).Execute()
+	fmt.Println(err)
+}
+
+func TestCommand_WithResourceLimitsTraced(t *testing.T) {
+	// Only the thread which started a traced process can wait for it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	require.NoError(t, err)
+	defer out.Close()
+
+	helper := exec.Command(os.Args[0], "-test.run=^TestResourceLimitsTracedHelper$")
+	helper.Env = append(os.Environ(), "CMD_TEST_TRACED=1")
+	helper.Stdout = out
+	helper.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+	require.NoError(t, helper.Start())
+
+	// The helper stops after exec, its threads and children are traced like by strace -f
+	pid := helper.Process.Pid
+	var status syscall.WaitStatus
+	_, err = syscall.Wait4(pid, &status, syscall.WALL, nil)
+	require.NoError(t, err)
+	require.NoError(t, syscall.PtraceSetOptions(pid, syscall.PTRACE_O_TRACECLONE|syscall.PTRACE_O_TRACEFORK|syscall.PTRACE_O_TRACEVFORK))
+	require.NoError(t, syscall.PtraceCont(pid, 0))
+
+	// Continue all traced processes after they stopped until the helper exited
+	for {
+		stopped, err := syscall.Wait4(-1, &status, syscall.WALL, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		require.NoError(t, err)
+		if stopped == pid && (status.Exited() || status.Signaled()) {
+			break
+		}
+		if !status.Stopped() {
+			continue
+		}
+		sig := status.StopSignal()
+		if sig == syscall.SIGTRAP || sig == syscall.SIGSTOP {
+			sig = 0
+		}
+		_ = syscall.PtraceCont(stopped, int(sig))
+	}
+
+	output, err := os.ReadFile(out.Name())
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "can not trace command to set resource limits, ptrace may not be permitted: fork/exec /bin/sh: operation not permitted")
+}
+
+func TestCommand_WithResourceLimitsInvalid(t *testing.T) {
+	// A soft limit above the hard limit is invalid
+	c := NewCommand("echo hello", WithResourceLimits(Limits{OpenFiles: 1 << 62}))
+	err := c.Execute()
+
+	assert.ErrorContains(t, err, "can not set open files limit")
+	assert.False(t, c.Executed())
+}
@@ -0,0 +1,6 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le && !sparc64
+
+package cmd
+
+// rlimitNproc is missing in package syscall, its value depends on the architecture
+const rlimitNproc = 0x6
@@ -0,0 +1,4 @@
+package cmd
+
+// rlimitNproc is missing in package syscall, its value depends on the architecture
+const rlimitNproc = 0x7
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FailurePolicy defines how a Sequence continues after a step failed
+type FailurePolicy int
+
+const (
+	// StopOnFailure skips all remaining steps after the first failed step
+	StopOnFailure FailurePolicy = iota
+	// ContinueOnFailure runs all steps and collects their results
+	ContinueOnFailure
+)
+
+// Sequence executes commands one after another
+type Sequence struct {
+	Commands []*Command
+	Policy   FailurePolicy
+	// Timeout is the total time budget which is shared by all steps
+	Timeout time.Duration
+}
+
+// SequenceResult holds the results of all steps in the order of execution
+type SequenceResult struct {
+	Steps []StepResult
+}
+
+// NewSequence creates a new sequence of the given commands
+// The default policy is StopOnFailure and there is no total timeout,
+// every step uses the timeout of its command
+//
+// Example:
+//
+//	s := cmd.NewSequence([]*cmd.Command{
+//	    cmd.NewCommand("mkdir -p build"),
+//	    cmd.NewCommand("make -C build"),
+//	}, cmd.WithTotalTimeout(10*time.Minute))
+//	result, err := s.Run(context.Background())
+func NewSequence(commands []*Command, options ...func(*Sequence)) *Sequence {
+	s := &Sequence{
+		Commands: commands,
+		Policy:   StopOnFailure,
+	}
+
+	for _, o := range options {
+		o(s)
+	}
+
+	return s
+}
+
+// WithFailurePolicy sets the policy which is applied if a step fails
+func WithFailurePolicy(p FailurePolicy) func(s *Sequence) {
+	return func(s *Sequence) {
+		s.Policy = p
+	}
+}
+
+// WithTotalTimeout sets the time budget for all steps of the sequence
+func WithTotalTimeout(t time.Duration) func(s *Sequence) {
+	return func(s *Sequence) {
+		s.Timeout = t
+	}
+}
+
+// Run executes the steps in order
+// A step fails if it returns an error or exits with an unexpected exit code.
+// The returned error joins a *StepError for every failed step.
+func (s *Sequence) Run(ctx context.Context) (*SequenceResult, error) {
+	_, hasDeadline := ctx.Deadline()
+	if s.Timeout > 0 && !hasDeadline {
+		subCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+		ctx = subCtx
+	}
+
+	result := &SequenceResult{Steps: make([]StepResult, 0, len(s.Commands))}
+	var errs []error
+	stop := false
+	for i, c := range s.Commands {
+		if stop || ctx.Err() != nil {
+			result.Steps = append(result.Steps, StepResult{Command: c, Skipped: true})
+			continue
+		}
+
+		step := executeStep(ctx, c)
+		result.Steps = append(result.Steps, step)
+		if step.Failed() {
+			errs = append(errs, step.asError(i))
+			stop = s.Policy == StopOnFailure
+		}
+	}
+
+	if s.Timeout > 0 && !hasDeadline && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		errs = append(errs, fmt.Errorf("sequence timed out after %v", s.Timeout))
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// Failed returns if at least one step failed
+func (r *SequenceResult) Failed() bool {
+	for _, step := range r.Steps {
+		if step.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a summary with one line per step
+//
+// Example:
+//
+//	[0] exit 0 (3ms): mkdir -p build
+//	[1] exit 2 (1.2s): make -C build
+//	[2] skipped: make -C build test
+func (r *SequenceResult) String() string {
+	var b strings.Builder
+	for i, step := range r.Steps {
+		switch {
+		case step.Skipped:
+			fmt.Fprintf(&b, "[%d] skipped: %s\n", i, step.Command.Command)
+		case step.Err != nil:
+			fmt.Fprintf(&b, "[%d] error (%v): %s: %v\n", i, step.Duration, step.Command.Command, step.Err)
+		default:
+			fmt.Fprintf(&b, "[%d] exit %d (%v): %s\n", i, step.ExitCode, step.Duration, step.Command.Command)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequence_Run(t *testing.T) {
+	s := NewSequence([]*Command{
+		NewCommand("echo first"),
+		NewCommand("echo second"),
+	})
+
+	result, err := s.Run(context.Background())
+
+	assert.Nil(t, err)
+	assert.False(t, result.Failed())
+	assert.Len(t, result.Steps, 2)
+	assert.Equal(t, "first\n", result.Steps[0].Stdout)
+	assert.Equal(t, "second\n", result.Steps[1].Stdout)
+	assert.Equal(t, 0, result.Steps[1].ExitCode)
+}
+
+func TestSequence_StopOnFailure(t *testing.T) {
+	s := NewSequence([]*Command{
+		NewCommand("echo first"),
+		NewCommand("exit 3"),
+		NewCommand("echo third"),
+	})
+
+	result, err := s.Run(context.Background())
+
+	var stepErr *StepError
+	assert.True(t, errors.As(err, &stepErr))
+	assert.Equal(t, 1, stepErr.Index)
+	assert.Equal(t, 3, stepErr.ExitCode)
+	assert.EqualError(t, err, "step 1 (exit 3) failed with exit code 3")
+
+	assert.True(t, result.Failed())
+	assert.Equal(t, 3, result.Steps[1].ExitCode)
+	assert.True(t, result.Steps[2].Skipped)
+	assert.False(t, result.Steps[2].Command.Executed())
+}
+
+func TestSequence_ContinueOnFailure(t *testing.T) {
+	s := NewSequence([]*Command{
+		NewCommand("exit 1"),
+		NewCommand("echo second"),
+		NewCommand("exit 2"),
+	}, WithFailurePolicy(ContinueOnFailure))
+
+	result, err := s.Run(context.Background())
+
+	assert.EqualError(t, err, "step 0 (exit 1) failed with exit code 1\nstep 2 (exit 2) failed with exit code 2")
+	assert.False(t, result.Steps[1].Skipped)
+	assert.Equal(t, "second\n", result.Steps[1].Stdout)
+	assert.Equal(t, 2, result.Steps[2].ExitCode)
+}
+
+func TestSequence_WithTotalTimeout(t *testing.T) {
+	s := NewSequence([]*Command{
+		NewCommand("sleep 0.05"),
+		NewCommand("sleep 1"),
+		NewCommand("echo never"),
+	}, WithTotalTimeout(200*time.Millisecond), WithFailurePolicy(ContinueOnFailure))
+
+	result, err := s.Run(context.Background())
+
+	assert.ErrorContains(t, err, "sequence timed out after 200ms")
+	assert.Nil(t, result.Steps[0].Err)
+	assert.ErrorIs(t, result.Steps[1].Err, context.DeadlineExceeded)
+	assert.True(t, result.Steps[2].Skipped)
+}
+
+func TestSequenceResult_String(t *testing.T) {
+	result := &SequenceResult{Steps: []StepResult{
+		{Command: NewCommand("echo hello"), Duration: time.Millisecond},
+		{Command: NewCommand("exit 2"), ExitCode: 2, Duration: 2 * time.Millisecond},
+		{Command: NewCommand("echo never"), Skipped: true},
+	}}
+
+	expected := "[0] exit 0 (1ms): echo hello\n" +
+		"[1] exit 2 (2ms): exit 2\n" +
+		"[2] skipped: echo never\n"
+	assert.Equal(t, expected, result.String())
+}
+
+func TestSequence_WithExpectedExitCodes(t *testing.T) {
+	s := NewSequence([]*Command{
+		NewCommand("exit 1", WithExpectedExitCodes(1)),
+		NewCommand("echo second"),
+	})
+
+	result, err := s.Run(context.Background())
+
+	assert.Nil(t, err)
+	assert.False(t, result.Failed())
+	assert.Equal(t, "second\n", result.Steps[1].Stdout)
+}
+
+func TestSequence_SkippedStepWithExpectedExitCodes(t *testing.T) {
+	s := NewSequence([]*Command{
+		NewCommand("exit 3"),
+		NewCommand("grep x /dev/null", WithExpectedExitCodes(1)),
+	})
+
+	result, err := s.Run(context.Background())
+
+	assert.EqualError(t, err, "step 0 (exit 3) failed with exit code 3")
+	assert.True(t, result.Steps[1].Skipped)
+	assert.False(t, result.Steps[1].Failed())
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SpecError describes an invalid value in a spec
+type SpecError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func newSpecError(node *yaml.Node, format string, args ...interface{}) *SpecError {
+	return &SpecError{
+		Line:   node.Line,
+		Column: node.Column,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// LoadSpec reads a YAML spec and creates a configured command for every entry
+// Unknown keys, wrong types and missing commands are reported with their line number.
+//
+// Example spec:
+//
+//	commands:
+//	  - command: make build
+//	    dir: /src
+//	    timeout: 10m
+//	    env:
+//	      GOFLAGS: -mod=vendor
+//	  - argv: [grep, -q, "error", build.log]
+//	    expected_exit_codes: [0, 1]
+//	  - command: cat
+//	    stdin: |
+//	      passed to stdin
+func LoadSpec(r io.Reader) ([]*Command, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("spec is empty")
+		}
+		return nil, err
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, newSpecError(root, "spec must be a mapping with a commands key")
+	}
+
+	var list *yaml.Node
+	err := forEachField(root, func(key, value *yaml.Node) error {
+		if key.Value != "commands" {
+			return newSpecError(key, "unknown key %q", key.Value)
+		}
+		list = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, newSpecError(root, "missing key \"commands\"")
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, newSpecError(list, "commands must be a list")
+	}
+
+	commands := make([]*Command, 0, len(list.Content))
+	for _, node := range list.Content {
+		c, err := commandFromSpec(node)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, c)
+	}
+
+	return commands, nil
+}
+
+func commandFromSpec(node *yaml.Node) (*Command, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, newSpecError(node, "command must be a mapping")
+	}
+
+	var command, argv *yaml.Node
+	var options []func(*Command)
+	err := forEachField(node, func(key, value *yaml.Node) error {
+		switch key.Value {
+		case "command":
+			if _, err := specString(value); err != nil {
+				return err
+			}
+			command = value
+		case "argv":
+			if value.Kind != yaml.SequenceNode || len(value.Content) == 0 {
+				return newSpecError(value, "argv must be a non-empty list")
+			}
+			argv = value
+		case "env":
+			env, err := specEnv(value)
+			if err != nil {
+				return err
+			}
+			options = append(options, func(c *Command) {
+				for _, e := range env {
+					c.AddEnv(e[0], e[1])
+				}
+			})
+		case "dir":
+			dir, err := specString(value)
+			if err != nil {
+				return err
+			}
+			options = append(options, WithWorkingDir(dir))
+		case "timeout":
+			s, err := specString(value)
+			if err != nil {
+				return err
+			}
+			timeout, err := time.ParseDuration(s)
+			if err != nil || timeout < 0 {
+				return newSpecError(value, "invalid timeout %q, expected a duration like 30s", s)
+			}
+			options = append(options, WithTimeout(timeout))
+		case "expected_exit_codes":
+			var codes []int
+			if value.Kind != yaml.SequenceNode {
+				return newSpecError(value, "expected_exit_codes must be a list of integers")
+			}
+			for _, item := range value.Content {
+				var code int
+				if item.Kind != yaml.ScalarNode || item.Tag != "!!int" || item.Decode(&code) != nil {
+					return newSpecError(item, "exit code must be an integer, got %q", item.Value)
+				}
+				codes = append(codes, code)
+			}
+			options = append(options, WithExpectedExitCodes(codes...))
+		case "stdin":
+			stdin, err := specString(value)
+			if err != nil {
+				return err
+			}
+			options = append(options, WithStdin(strings.NewReader(stdin)))
+		default:
+			return newSpecError(key, "unknown key %q", key.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case command != nil && argv != nil:
+		return nil, newSpecError(node, "command and argv can not be used together")
+	case command != nil:
+		return NewCommand(command.Value, options...), nil
+	case argv != nil:
+		args := make([]string, 0, len(argv.Content))
+		for _, item := range argv.Content {
+			arg, err := specString(item)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return NewCommandArgs(args, options...), nil
+	}
+
+	return nil, newSpecError(node, "either command or argv is required")
+}
+
+// forEachField calls fn for every key value pair of a mapping node
+// and fails on duplicate keys
+func forEachField(node *yaml.Node, fn func(key, value *yaml.Node) error) error {
+	seen := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if seen[key.Value] {
+			return newSpecError(key, "duplicate key %q", key.Value)
+		}
+		seen[key.Value] = true
+
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func specString(node *yaml.Node) (string, error) {
+	if node.Kind != yaml.ScalarNode || node.Tag == "!!null" {
+		return "", newSpecError(node, "expected a string")
+	}
+	return node.Value, nil
+}
+
+// specEnv returns the env variables as key value pairs in the order of the spec
+func specEnv(node *yaml.Node) ([][2]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, newSpecError(node, "env must be a mapping")
+	}
+
+	var env [][2]string
+	err := forEachField(node, func(key, value *yaml.Node) error {
+		v, err := specString(value)
+		if err != nil {
+			return err
+		}
+		env = append(env, [2]string{key.Value, v})
+		return nil
+	})
+	return env, err
+}
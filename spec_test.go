@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSpec(t *testing.T) {
+	spec := `
+commands:
+  - command: make build
+    dir: /src
+    timeout: 10m
+    env:
+      GOFLAGS: -mod=vendor
+      PORT: 8080
+  - argv: [grep, -q, "some error", build.log]
+    expected_exit_codes: [0, 1]
+  - command: cat
+    stdin: |
+      passed to stdin
+`
+
+	commands, err := LoadSpec(strings.NewReader(spec))
+
+	require.NoError(t, err)
+	require.Len(t, commands, 3)
+
+	assert.Equal(t, "make build", commands[0].Command)
+	assert.Nil(t, commands[0].Args)
+	assert.Equal(t, "/src", commands[0].WorkingDir)
+	assert.Equal(t, 10*time.Minute, commands[0].Timeout)
+	assert.Equal(t, []string{"GOFLAGS=-mod=vendor", "PORT=8080"}, commands[0].Env)
+
+	assert.Equal(t, []string{"grep", "-q", "some error", "build.log"}, commands[1].Args)
+	assert.Equal(t, []int{0, 1}, commands[1].expectedExitCodes)
+	assert.Equal(t, 30*time.Minute, commands[1].Timeout)
+
+	assert.NotNil(t, commands[2].StdinReader)
+}
+
+func TestLoadSpec_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		err  string
+	}{
+		{"empty", "", "spec is empty"},
+		{"no mapping", "- echo", "line 1, column 1: spec must be a mapping with a commands key"},
+		{"missing commands", "other: 1", `line 1, column 1: unknown key "other"`},
+		{"commands no list", "commands: echo", "line 1, column 11: commands must be a list"},
+		{"unknown key", "commands:\n  - command: echo\n    user: root", `line 3, column 5: unknown key "user"`},
+		{"duplicate key", "commands:\n  - command: echo\n    command: ls", `line 3, column 5: duplicate key "command"`},
+		{"missing command", "commands:\n  - dir: /tmp", "line 2, column 5: either command or argv is required"},
+		{"command and argv", "commands:\n  - command: ls\n    argv: [ls]", "line 2, column 5: command and argv can not be used together"},
+		{"empty argv", "commands:\n  - argv: []", "line 2, column 11: argv must be a non-empty list"},
+		{"command list", "commands:\n  - command: [ls]", "line 2, column 14: expected a string"},
+		{"invalid timeout", "commands:\n  - command: ls\n    timeout: 10", `line 3, column 14: invalid timeout "10", expected a duration like 30s`},
+		{"invalid exit code", "commands:\n  - command: ls\n    expected_exit_codes: [0, one]", `line 3, column 30: exit code must be an integer, got "one"`},
+		{"env list", "commands:\n  - command: ls\n    env: [A=B]", "line 3, column 10: env must be a mapping"},
+		{"env without value", "commands:\n  - command: ls\n    env:\n      A:", "line 4, column 9: expected a string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSpec(strings.NewReader(tt.spec))
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadSpec_SpecError(t *testing.T) {
+	_, err := LoadSpec(strings.NewReader("commands:\n  - command: ls\n    unknown: true"))
+
+	var specErr *SpecError
+	require.True(t, errors.As(err, &specErr))
+	assert.Equal(t, 3, specErr.Line)
+	assert.Equal(t, 5, specErr.Column)
+}
+
+func TestLoadSpec_SyntaxError(t *testing.T) {
+	_, err := LoadSpec(strings.NewReader("commands:\n  - command: ls\n\t- broken"))
+
+	assert.ErrorContains(t, err, "yaml: line 2")
+}
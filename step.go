@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StepResult holds the result of a command which was executed as
+// a step of a Sequence or a Group
+type StepResult struct {
+	Command  *Command
+	ExitCode int
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
+	Combined string
+	// Err is the error returned by ExecuteContext
+	Err error
+	// Skipped is true if the step was not run at all
+	Skipped bool
+}
+
+// Failed returns if the step returned an error or exited with
+// an exit code which is not expected by the command, see WithExpectedExitCodes.
+// Skipped steps did not fail.
+func (r StepResult) Failed() bool {
+	if r.Skipped {
+		return false
+	}
+	if r.Err != nil {
+		return true
+	}
+	if r.Command != nil {
+		return !r.Command.isExpectedExitCode(r.ExitCode)
+	}
+	return r.ExitCode != 0
+}
+
+// StepError describes a failed step
+type StepError struct {
+	Index    int
+	Command  string
+	ExitCode int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("step %d (%s) failed: %v", e.Index, e.Command, e.Err)
+	}
+	return fmt.Sprintf("step %d (%s) failed with exit code %d", e.Index, e.Command, e.ExitCode)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+func executeStep(ctx context.Context, c *Command) StepResult {
+	start := time.Now()
+	err := c.ExecuteContext(ctx)
+
+	return StepResult{
+		Command:  c,
+		ExitCode: c.exitCode,
+		Duration: time.Since(start),
+		Stdout:   c.stdout.String(),
+		Stderr:   c.stderr.String(),
+		Combined: c.combined.String(),
+		Err:      err,
+	}
+}
+
+func (r StepResult) asError(index int) error {
+	if r.Skipped || !r.Failed() {
+		return nil
+	}
+	return &StepError{
+		Index:    index,
+		Command:  r.Command.Command,
+		ExitCode: r.ExitCode,
+		Err:      r.Err,
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var (
+	errTempDirWithWorkingDir = errors.New("WithTempWorkingDir can not be combined with WithWorkingDir")
+	errTempDirWithChroot     = errors.New("WithTempWorkingDir can not be combined with WithChroot")
+)
+
+// TempDir configures the temporary working dir of a command
+type TempDir struct {
+	// Pattern is passed to os.MkdirTemp to create the dir
+	Pattern string
+	// Files are copied into the dir before the command is started
+	Files fs.FS
+	// KeepOnFailure keeps the dir if the command failed to run or exited
+	// with an unexpected exit code
+	KeepOnFailure bool
+	path          string
+}
+
+// WithTempWorkingDir creates a new temporary working dir every time the command is executed
+// The dir is removed after the execution, its path can be read with TempDir.
+// It can not be combined with WithWorkingDir or WithChroot.
+//
+// Example:
+//
+//	//go:embed testdata
+//	var testdata embed.FS
+//
+//	c := cmd.NewCommand("sh testdata/build.sh", cmd.WithTempWorkingDir("build-*",
+//	    cmd.WithTempDirFiles(testdata),
+//	    cmd.WithKeepOnFailure,
+//	))
+func WithTempWorkingDir(pattern string, options ...func(*TempDir)) func(c *Command) {
+	return func(c *Command) {
+		t := &TempDir{Pattern: pattern}
+		for _, o := range options {
+			o(t)
+		}
+		c.tempDir = t
+
+		if c.WorkingDir != "" {
+			c.addOptionError(errTempDirWithWorkingDir)
+		}
+		if c.chroot != "" {
+			c.addOptionError(errTempDirWithChroot)
+		}
+	}
+}
+
+// WithTempDirFiles copies the files into the temporary working dir,
+// files with any executable bit set are executable by the command
+func WithTempDirFiles(files fs.FS) func(t *TempDir) {
+	return func(t *TempDir) {
+		t.Files = files
+	}
+}
+
+// WithKeepOnFailure keeps the temporary working dir if the command failed
+func WithKeepOnFailure(t *TempDir) {
+	t.KeepOnFailure = true
+}
+
+// TempDir returns the path of the temporary working dir which was created
+// by the last execution, see WithTempWorkingDir
+func (c *Command) TempDir() string {
+	if c.tempDir == nil {
+		return ""
+	}
+	return c.tempDir.path
+}
+
+// create creates the dir and copies the files into it
+func (t *TempDir) create() (string, error) {
+	dir, err := os.MkdirTemp("", t.Pattern)
+	if err != nil {
+		return "", err
+	}
+	t.path = dir
+
+	if t.Files == nil {
+		return dir, nil
+	}
+
+	err = fs.WalkDir(t.Files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(t.Files, path)
+		if err != nil {
+			return err
+		}
+
+		var perm os.FileMode = 0644
+		if info.Mode()&0111 != 0 {
+			perm = 0755
+		}
+		return os.WriteFile(target, data, perm)
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// remove removes the dir unless it should be kept after a failure
+func (t *TempDir) remove(failed bool) error {
+	if failed && t.KeepOnFailure {
+		return nil
+	}
+	return os.RemoveAll(t.path)
+}
@@ -0,0 +1,78 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_WithTempWorkingDir(t *testing.T) {
+	c := NewCommand("pwd; touch created", WithTempWorkingDir("cmd-test-*"))
+	assert.Equal(t, "", c.TempDir())
+
+	err := c.Execute()
+
+	require.NoError(t, err)
+	dir := c.TempDir()
+	assert.True(t, strings.HasPrefix(filepath.Base(dir), "cmd-test-"))
+	assert.Equal(t, dir+"\n", c.Stdout())
+	assert.NoDirExists(t, dir)
+	assert.Equal(t, "", c.WorkingDir)
+	assert.Equal(t, "", c.Result().WorkingDir)
+}
+
+func TestCommand_WithTempWorkingDirConflicts(t *testing.T) {
+	c := NewCommand("pwd", WithWorkingDir("/tmp"), WithTempWorkingDir(""))
+	assert.ErrorIs(t, c.Execute(), errTempDirWithWorkingDir)
+	assert.False(t, c.Executed())
+
+	c = NewCommand("pwd", WithTempWorkingDir(""), WithWorkingDir("/tmp"))
+	assert.ErrorIs(t, c.Execute(), errTempDirWithWorkingDir)
+	assert.False(t, c.Executed())
+}
+
+func TestCommand_WithTempWorkingDirFiles(t *testing.T) {
+	files := fstest.MapFS{
+		"config.txt":      {Data: []byte("config")},
+		"bin/run.sh":      {Data: []byte("#!/bin/sh\ncat config.txt\n"), Mode: 0755},
+		"empty/dir/.keep": {Data: []byte{}},
+	}
+
+	c := NewCommand("./bin/run.sh && test -d empty/dir", WithTempWorkingDir("", WithTempDirFiles(files)))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.ExitCode())
+	assert.Equal(t, "config", c.Stdout())
+}
+
+func TestCommand_WithTempWorkingDirNewDirPerExecution(t *testing.T) {
+	tempDir := WithTempWorkingDir("")
+	first := NewCommand("true", tempDir)
+	second := NewCommand("true", tempDir)
+
+	require.NoError(t, first.Execute())
+	require.NoError(t, second.Execute())
+
+	assert.NotEqual(t, first.TempDir(), second.TempDir())
+}
+
+func TestCommand_WithKeepOnFailure(t *testing.T) {
+	c := NewCommand("echo log > build.log; exit 2", WithTempWorkingDir("", WithKeepOnFailure))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	defer os.RemoveAll(c.TempDir())
+	assert.FileExists(t, filepath.Join(c.TempDir(), "build.log"))
+
+	c = NewCommand("exit 0", WithTempWorkingDir("", WithKeepOnFailure))
+	require.NoError(t, c.Execute())
+	assert.NoDirExists(t, c.TempDir())
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// TraceParentEnv is the env variable which propagates the trace context to the command
+const TraceParentEnv = "TRACEPARENT"
+
+// Tracer starts spans around the execution of commands, see WithTracer
+// It can be implemented by adapters for tracing libraries, see MemoryTracer for an example.
+type Tracer interface {
+	// Start starts a span which is a child of the span in ctx, if there is one.
+	// The returned context contains the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	SetAttributes(attrs ...slog.Attr)
+	RecordError(err error)
+	End()
+	// TraceParent returns the W3C traceparent of the span, e.g.
+	// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01,
+	// or an empty string if the trace context should not be propagated
+	TraceParent() string
+}
+
+// WithTracer starts a span around every execution of the command
+// The span is named after the executable of the command, or the shell if it can not
+// be determined. The trace context is propagated to the process with the TRACEPARENT env variable,
+// the env of the command is not modified.
+// The span has the command, exit code and signal as attributes. Errors and unexpected
+// exit codes are recorded as errors.
+//
+// Example:
+//
+//	c := cmd.NewCommand("./migrate.sh", cmd.WithTracer(tracer))
+//	c.ExecuteContext(ctx)
+func WithTracer(tracer Tracer) func(c *Command) {
+	return WithMiddleware(func(next Executor) Executor {
+		return func(ctx context.Context, c *Command) error {
+			name := c.executable()
+			if name == "" {
+				name = filepath.Base(c.baseCommand.Path)
+			}
+			ctx, span := tracer.Start(ctx, name)
+			defer span.End()
+
+			span.SetAttributes(slog.String("command", c.Command))
+			if c.WorkingDir != "" {
+				span.SetAttributes(slog.String("working_dir", c.WorkingDir))
+			}
+			c.traceParent = span.TraceParent()
+			defer func() { c.traceParent = "" }()
+
+			err := next(ctx, c)
+			switch {
+			case err != nil:
+				span.RecordError(err)
+			case !c.isExpectedExitCode(c.exitCode):
+				span.RecordError(fmt.Errorf("command exited with unexpected exit code %d", c.exitCode))
+			}
+			if c.executed {
+				span.SetAttributes(slog.Int("exit_code", c.exitCode))
+			}
+			if c.signal != 0 {
+				span.SetAttributes(slog.String("signal", c.signal.String()))
+			}
+			return err
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// MemoryTracer is a Tracer which keeps the spans in memory, e.g. to test tracing
+//
+// Example:
+//
+//	tracer := &cmd.MemoryTracer{}
+//	c := cmd.NewCommand("echo hello", cmd.WithTracer(tracer))
+//	c.Execute()
+//	span := tracer.Spans()[0]
+type MemoryTracer struct {
+	mu    sync.Mutex
+	spans []*MemorySpan
+}
+
+// MemorySpan is a span of a MemoryTracer
+type MemorySpan struct {
+	Name string
+	// TraceID and SpanID are hex encoded, ParentSpanID is empty for root spans
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Start        time.Time
+	// End is zero until the span ended
+	End        time.Time
+	Attributes []slog.Attr
+	Errors     []error
+	mu         sync.Mutex
+}
+
+type memorySpanKey struct{}
+
+// Start starts a span which is a child of the MemorySpan in ctx
+func (t *MemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &MemorySpan{Name: name, SpanID: randomHex(8), Start: time.Now()}
+	if parent, ok := ctx.Value(memorySpanKey{}).(*MemorySpan); ok {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.TraceID = randomHex(16)
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, memorySpanKey{}, span), memorySpanHandle{span}
+}
+
+// Spans returns the started spans in the order they were started
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*MemorySpan{}, t.spans...)
+}
+
+// Attribute returns the value of an attribute of the span
+func (s *MemorySpan) Attribute(key string) (slog.Value, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.Attributes) - 1; i >= 0; i-- {
+		if s.Attributes[i].Key == key {
+			return s.Attributes[i].Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// Ended reports if the span ended
+func (s *MemorySpan) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.End.IsZero()
+}
+
+// memorySpanHandle implements Span, the methods of the interface
+// would conflict with the fields of MemorySpan
+type memorySpanHandle struct {
+	span *MemorySpan
+}
+
+func (h memorySpanHandle) SetAttributes(attrs ...slog.Attr) {
+	h.span.mu.Lock()
+	defer h.span.mu.Unlock()
+	h.span.Attributes = append(h.span.Attributes, attrs...)
+}
+
+func (h memorySpanHandle) RecordError(err error) {
+	h.span.mu.Lock()
+	defer h.span.mu.Unlock()
+	h.span.Errors = append(h.span.Errors, err)
+}
+
+func (h memorySpanHandle) End() {
+	h.span.mu.Lock()
+	defer h.span.mu.Unlock()
+	if h.span.End.IsZero() {
+		h.span.End = time.Now()
+	}
+}
+
+func (h memorySpanHandle) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-01", h.span.TraceID, h.span.SpanID)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,146 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_WithTracer(t *testing.T) {
+	tracer := &MemoryTracer{}
+	c := NewCommand("echo $TRACEPARENT", WithTracer(tracer), WithWorkingDir("/tmp"))
+	err := c.Execute()
+
+	require.NoError(t, err)
+	spans := tracer.Spans()
+	require.Len(t, spans, 1)
+
+	span := spans[0]
+	// echo is a shell builtin
+	assert.Equal(t, "sh", span.Name)
+	assert.Len(t, span.TraceID, 32)
+	assert.Len(t, span.SpanID, 16)
+	assert.Empty(t, span.ParentSpanID)
+	assert.True(t, span.Ended())
+	assert.Empty(t, span.Errors)
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01\n", span.TraceID, span.SpanID), c.Stdout())
+
+	command, _ := span.Attribute("command")
+	assert.Equal(t, "echo $TRACEPARENT", command.String())
+	dir, _ := span.Attribute("working_dir")
+	assert.Equal(t, "/tmp", dir.String())
+	exitCode, _ := span.Attribute("exit_code")
+	assert.Equal(t, int64(0), exitCode.Int64())
+	_, ok := span.Attribute("signal")
+	assert.False(t, ok)
+}
+
+func TestCommand_WithTracerDoesNotModifyEnv(t *testing.T) {
+	tracer := &MemoryTracer{}
+	c := NewCommand("echo $TRACEPARENT", WithTracer(tracer), WithEnvironmentVariables(EnvVars{"A": "1"}))
+
+	require.NoError(t, c.Execute())
+	first := c.Stdout()
+	assert.Equal(t, []string{"A=1"}, c.Env)
+	assert.Equal(t, []string{"A=1"}, c.Result().Env)
+
+	rerun := c.Result().NewCommand(WithTracer(tracer))
+	require.NoError(t, rerun.Execute())
+	assert.NotEqual(t, first, rerun.Stdout())
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01\n", tracer.Spans()[1].TraceID, tracer.Spans()[1].SpanID), rerun.Stdout())
+}
+
+func TestCommand_WithTracerInheritsEnv(t *testing.T) {
+	t.Setenv("CMD_TEST_INHERITED", "inherited")
+	c := NewCommand("echo $CMD_TEST_INHERITED $TRACEPARENT", WithTracer(&MemoryTracer{}))
+	// Without env the process inherits the env of the current process
+	c.Env = nil
+
+	require.NoError(t, c.Execute())
+	assert.Regexp(t, "^inherited 00-[0-9a-f]{32}-[0-9a-f]{16}-01\n$", c.Stdout())
+	assert.Nil(t, c.Env)
+}
+
+func TestCommand_WithTracerChildSpan(t *testing.T) {
+	tracer := &MemoryTracer{}
+	ctx, parent := tracer.Start(context.Background(), "deploy")
+
+	for _, command := range []string{"echo first", "echo second"} {
+		c := NewCommand(command, WithTracer(tracer))
+		require.NoError(t, c.ExecuteContext(ctx))
+	}
+	parent.End()
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 3)
+	for _, span := range spans[1:] {
+		assert.Equal(t, spans[0].TraceID, span.TraceID)
+		assert.Equal(t, spans[0].SpanID, span.ParentSpanID)
+	}
+	assert.NotEqual(t, spans[1].SpanID, spans[2].SpanID)
+}
+
+func TestCommand_WithTracerRecordsErrors(t *testing.T) {
+	tracer := &MemoryTracer{}
+
+	c := NewCommand("exit 3", WithTracer(tracer))
+	require.NoError(t, c.Execute())
+
+	c = NewCommand("sleep 1", WithTracer(tracer), WithTimeout(10*time.Millisecond))
+	timeoutErr := c.Execute()
+	require.Error(t, timeoutErr)
+
+	c = NewCommand("kill -9 $$", WithTracer(tracer))
+	require.NoError(t, c.Execute())
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 3)
+
+	assert.Equal(t, []error{errors.New("command exited with unexpected exit code 3")}, spans[0].Errors)
+	exitCode, _ := spans[0].Attribute("exit_code")
+	assert.Equal(t, int64(3), exitCode.Int64())
+
+	assert.Equal(t, "sleep", spans[1].Name)
+	assert.Equal(t, []error{timeoutErr}, spans[1].Errors)
+
+	signal, _ := spans[2].Attribute("signal")
+	assert.Equal(t, "killed", signal.String())
+	for _, span := range spans {
+		assert.True(t, span.Ended())
+	}
+}
+
+func TestCommand_WithTracerOptionError(t *testing.T) {
+	tracer := &MemoryTracer{}
+	c := NewCommand("echo hello", WithTracer(tracer), WithExpectedExitCodes(0), WithEnvFile("/cmd-does-not-exist.env"))
+	err := c.Execute()
+
+	require.Error(t, err)
+	span := tracer.Spans()[0]
+	assert.Equal(t, []error{err}, span.Errors)
+	_, ok := span.Attribute("exit_code")
+	assert.False(t, ok)
+}
+
+func TestMemorySpan_Attribute(t *testing.T) {
+	tracer := &MemoryTracer{}
+	_, span := tracer.Start(context.Background(), "test")
+	span.SetAttributes(slog.Int("count", 1), slog.String("name", "first"))
+	span.SetAttributes(slog.Int("count", 2))
+
+	s := tracer.Spans()[0]
+	count, ok := s.Attribute("count")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), count.Int64())
+	_, ok = s.Attribute("missing")
+	assert.False(t, ok)
+	assert.False(t, s.Ended())
+}